@@ -0,0 +1,349 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/gogap/factory"
+	"net/http"
+	"strings"
+)
+
+const maskedValue = factory.MaskedValue
+
+// DefaultSecretPatterns are the case-insensitive substrings of the property and
+// option keys whose values are masked
+var DefaultSecretPatterns = factory.DefaultSecretPatterns
+
+type Option struct {
+	f func(p *Handler)
+}
+
+// OptOfGuard allow the POST actions only if the guard returns true, all the
+// actions are forbidden without guard
+func OptOfGuard(guard func(r *http.Request) bool) Option {
+	return Option{func(p *Handler) {
+		p.guard = guard
+	}}
+}
+
+// OptOfSecretPatterns replace the DefaultSecretPatterns
+func OptOfSecretPatterns(patterns ...string) Option {
+	return Option{func(p *Handler) {
+		p.secretPatterns = patterns
+	}}
+}
+
+// Handler serves the state of factory in JSON:
+//
+//	GET  /definitions
+//	GET  /instances
+//	GET  /graph
+//	GET  /profiles
+//	GET  /properties
+//	GET  /health
+//	POST /singletons/{name}/refresh
+//	POST /singletons/{name}/destroy
+//
+// the routes respond 501 if the factory does not implement factory.Introspector
+// or factory.Lifecycle
+type Handler struct {
+	introspector   factory.Introspector
+	lifecycle      factory.Lifecycle
+	guard          func(r *http.Request) bool
+	secretPatterns []string
+}
+
+func NewHandler(f factory.Factory, opts ...Option) *Handler {
+	handler := &Handler{
+		secretPatterns: DefaultSecretPatterns,
+	}
+
+	handler.introspector, _ = f.(factory.Introspector)
+	handler.lifecycle, _ = f.(factory.Lifecycle)
+
+	for _, opt := range opts {
+		opt.f(handler)
+	}
+
+	return handler
+}
+
+type DefinitionInfo struct {
+	Name     string   `json:"name"`
+	Scope    string   `json:"scope"`
+	Model    string   `json:"model"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Profiles []string `json:"profiles,omitempty"`
+	Active   bool     `json:"active"`
+	LazyInit bool     `json:"lazy_init,omitempty"`
+	Keyed    bool     `json:"keyed,omitempty"`
+}
+
+type InstanceInfo struct {
+	ID         string                 `json:"id"`
+	Definition string                 `json:"definition"`
+	Key        string                 `json:"key,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+}
+
+type HealthCheckInfo struct {
+	Definition string `json:"definition"`
+	Key        string `json:"key,omitempty"`
+	Critical   bool   `json:"critical"`
+	Duration   string `json:"duration"`
+	Error      string `json:"error,omitempty"`
+}
+
+type HealthInfo struct {
+	Status string            `json:"status"`
+	Checks []HealthCheckInfo `json:"checks"`
+}
+
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to,omitempty"`
+	Field string `json:"field"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+type Graph struct {
+	Nodes []string    `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+func (p *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	path := strings.Trim(r.URL.Path, "/")
+
+	if strings.HasPrefix(path, "singletons/") {
+		p.serveAction(w, r, strings.TrimPrefix(path, "singletons/"))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if path == "health" {
+		p.serveHealth(w, r)
+		return
+	}
+
+	if p.introspector == nil {
+		writeError(w, http.StatusNotImplemented, "factory is not introspector")
+		return
+	}
+
+	switch path {
+	case "definitions":
+		writeJSON(w, http.StatusOK, p.definitions())
+	case "instances":
+		writeJSON(w, http.StatusOK, p.instances())
+	case "graph":
+		writeJSON(w, http.StatusOK, p.graph())
+	case "profiles":
+		writeJSON(w, http.StatusOK, map[string][]string{"active": p.introspector.ActiveProfiles()})
+	case "properties":
+		writeJSON(w, http.StatusOK, p.properties())
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (p *Handler) serveAction(w http.ResponseWriter, r *http.Request, action string) {
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if p.guard == nil || !p.guard(r) {
+		writeError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	if p.lifecycle == nil {
+		writeError(w, http.StatusNotImplemented, "factory is not lifecycle")
+		return
+	}
+
+	idx := strings.LastIndex(action, "/")
+	if idx <= 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	name, verb := action[:idx], action[idx+1:]
+
+	var err error
+	switch verb {
+	case "refresh":
+		err = p.lifecycle.Refresh(name)
+	case "destroy":
+		err = p.lifecycle.DestroySingleton(name)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"name": name, "action": verb})
+	case factory.ErrObjectDefintionNotExist.IsEqual(err):
+		writeError(w, http.StatusNotFound, err.Error())
+	case factory.ErrObjectIsNotSingleton.IsEqual(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// serveHealth responds 503 if any check of critical definition failed
+func (p *Handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+
+	if p.lifecycle == nil {
+		writeError(w, http.StatusNotImplemented, "factory is not lifecycle")
+		return
+	}
+
+	report := p.lifecycle.Health(r.Context())
+
+	info := HealthInfo{Status: report.Status.String(), Checks: []HealthCheckInfo{}}
+
+	for _, check := range report.Checks {
+		checkInfo := HealthCheckInfo{
+			Definition: check.Definition,
+			Key:        check.Key,
+			Critical:   check.Critical,
+			Duration:   check.Duration.String(),
+		}
+
+		if check.Err != nil {
+			checkInfo.Error = check.Err.Error()
+		}
+
+		info.Checks = append(info.Checks, checkInfo)
+	}
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, info)
+}
+
+func (p *Handler) definitions() (infos []DefinitionInfo) {
+
+	active := make(map[string]bool)
+	for _, profile := range p.introspector.ActiveProfiles() {
+		active[profile] = true
+	}
+
+	infos = []DefinitionInfo{}
+
+	for _, def := range p.introspector.Definitions() {
+		info := DefinitionInfo{
+			Name:     def.Name(),
+			Scope:    def.Scope().String(),
+			Model:    def.Type().PkgPath() + "::" + def.Type().String(),
+			Aliases:  def.Aliases(),
+			Tags:     def.Tags(),
+			Profiles: def.Profiles(),
+			Active:   len(def.Profiles()) == 0,
+			LazyInit: def.IsLazyInit(),
+			Keyed:    def.IsKeyedSingleton(),
+		}
+
+		for _, profile := range def.Profiles() {
+			if active[profile] {
+				info.Active = true
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return
+}
+
+func (p *Handler) instances() (infos []InstanceInfo) {
+
+	infos = []InstanceInfo{}
+
+	for _, objIns := range p.introspector.Instances() {
+		def := objIns.Definition()
+
+		infos = append(infos, InstanceInfo{
+			ID:         objIns.Id(),
+			Definition: def.Name(),
+			Key:        objIns.Key(),
+			Options:    p.mask(objIns.Options()),
+		})
+	}
+
+	return
+}
+
+// graph is built from the refs of active definitions
+func (p *Handler) graph() (graph Graph) {
+
+	graph = Graph{Nodes: []string{}, Edges: []GraphEdge{}}
+
+	for _, def := range p.introspector.Definitions() {
+		refs, err := p.introspector.GetRefs(def.Name())
+		if err != nil {
+			continue
+		}
+
+		graph.Nodes = append(graph.Nodes, def.Name())
+
+		for _, ref := range refs {
+			edge := GraphEdge{From: def.Name(), Field: ref.Field, State: ref.State.String()}
+			if ref.Error != nil {
+				edge.Error = ref.Error.Error()
+			}
+
+			if len(ref.Members) == 0 {
+				edge.To = ref.Definition
+				graph.Edges = append(graph.Edges, edge)
+				continue
+			}
+
+			for _, member := range ref.Members {
+				edge.To = member
+				graph.Edges = append(graph.Edges, edge)
+			}
+		}
+	}
+
+	return
+}
+
+func (p *Handler) properties() map[string]interface{} {
+	env := p.introspector.Environment()
+
+	properties := make(map[string]interface{})
+	for _, key := range env.Keys() {
+		properties[key], _ = env.Property(key)
+	}
+
+	return p.mask(properties)
+}
+
+// mask returns a copy of values, the values of secret keys are masked
+func (p *Handler) mask(values map[string]interface{}) map[string]interface{} {
+	return factory.MaskSecrets(values, p.secretPatterns)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gogap/factory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testEngine struct {
+	Power int
+}
+
+func (p *testEngine) Health(ctx context.Context) error {
+	if p.Power < 0 {
+		return errors.New("engine stalled")
+	}
+	return nil
+}
+
+type testCar struct {
+	Engine *testEngine
+}
+
+func init() {
+	factory.RegisterModel((*testEngine)(nil), "adminTestEngine")
+	factory.RegisterModel((*testCar)(nil), "adminTestCar")
+}
+
+func newTestServer(t *testing.T) (f *factory.ClassicFactory, server *httptest.Server) {
+
+	var err error
+
+	f = factory.NewClassicFactory(nil)
+	f.Environment().AddFirst(factory.NewMapPropertySource("test", map[string]interface{}{
+		"db": map[string]interface{}{"host": "localhost", "password": "123456"},
+	}))
+
+	if err = f.Define("engine", factory.Singleton, "adminTestEngine", factory.DefOptOfCritical()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Define("car", factory.Singleton, "adminTestCar",
+		factory.DefOptOfObjectRef("Engine", "engine"),
+		factory.DefOptOfDefaultOptions(factory.Options{"owner": "gogap", "token": "abc"}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(f, OptOfGuard(func(r *http.Request) bool {
+		return r.Header.Get("X-Admin-Token") == "admin"
+	}))
+
+	server = httptest.NewServer(http.StripPrefix("/admin", handler))
+
+	return
+}
+
+func TestHandlerGet(t *testing.T) {
+
+	_, server := newTestServer(t)
+	defer server.Close()
+
+	get := func(path string, v interface{}) {
+		resp, err := http.Get(server.URL + "/admin/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s should be ok, got: %d", path, resp.StatusCode)
+		}
+
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var defs []DefinitionInfo
+	get("definitions", &defs)
+
+	if len(defs) != 2 || defs[0].Name != "car" || defs[0].Scope != "singleton" || !defs[0].Active {
+		t.Errorf("bad definitions: %+v", defs)
+		return
+	}
+
+	var instances []InstanceInfo
+	get("instances", &instances)
+
+	if len(instances) != 2 || instances[0].Options["owner"] != "gogap" || instances[0].Options["token"] != maskedValue {
+		t.Errorf("bad instances: %+v", instances)
+		return
+	}
+
+	var graph Graph
+	get("graph", &graph)
+
+	if len(graph.Edges) != 1 || graph.Edges[0] != (GraphEdge{From: "car", To: "engine", Field: "Engine", State: "resolved"}) {
+		t.Errorf("bad graph: %+v", graph)
+		return
+	}
+
+	var properties map[string]interface{}
+	get("properties", &properties)
+
+	if properties["db.host"] != "localhost" || properties["db.password"] != maskedValue {
+		t.Errorf("bad properties: %v", properties)
+		return
+	}
+
+	var health HealthInfo
+	get("health", &health)
+
+	if health.Status != "up" || len(health.Checks) != 1 || health.Checks[0].Definition != "engine" || !health.Checks[0].Critical {
+		t.Errorf("bad health: %+v", health)
+		return
+	}
+}
+
+func TestHandlerHealthDown(t *testing.T) {
+
+	f, server := newTestServer(t)
+	defer server.Close()
+
+	obj, err := f.GetObject("engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.(*testEngine).Power = -1
+
+	resp, err := http.Get(server.URL + "/admin/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health should be unavailable if critical check failed, got: %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestHandlerActions(t *testing.T) {
+
+	f, server := newTestServer(t)
+	defer server.Close()
+
+	post := func(path string, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/admin/"+path, strings.NewReader(""))
+		req.Header.Set("X-Admin-Token", token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if status := post("singletons/engine/destroy", "bad"); status != http.StatusForbidden {
+		t.Errorf("the action without token should be forbidden, got: %d", status)
+		return
+	}
+
+	if status := post("singletons/engine/destroy", "admin"); status != http.StatusOK {
+		t.Errorf("destroy should be ok, got: %d", status)
+		return
+	}
+
+	if len(f.Instances()) != 1 {
+		t.Errorf("the singleton should be destroyed, got: %v", f.Instances())
+		return
+	}
+
+	if status := post("singletons/car/refresh", "admin"); status != http.StatusOK {
+		t.Errorf("refresh should be ok, got: %d", status)
+		return
+	}
+
+	if status := post("singletons/notExist/refresh", "admin"); status != http.StatusNotFound {
+		t.Errorf("refresh not exist singleton should be not found, got: %d", status)
+		return
+	}
+}
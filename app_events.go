@@ -0,0 +1,179 @@
+package factory
+
+import (
+	"github.com/gogap/errors"
+	"reflect"
+	"sync"
+)
+
+const defaultEventListenerMethod = "OnEvent"
+
+var publisherType = reflect.TypeOf((*Publisher)(nil)).Elem()
+
+// Publisher publish the application events to the singletons listening the
+// type of event, it is injected into the nil exported fields of Publisher type
+type Publisher interface {
+	// Publish call the listeners in the current goroutine, and stops at the first error
+	Publish(event interface{}) error
+	// PublishAsync call the listeners in a new goroutine, the errors are ignored
+	PublishAsync(event interface{})
+}
+
+// Listener is the singleton receiving the events of type E, the event of the
+// types assignable to E, such as the implementations of interface E, are received too
+type Listener[E any] interface {
+	OnEvent(event E) error
+}
+
+// DefOptOfEventListener mark the method as the listener of the type of its parameter,
+// the method should be func(E) or func(E) error
+func DefOptOfEventListener(methodName string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.eventListeners = append(od.eventListeners, methodName)
+		return
+	}}
+}
+
+func (p *ObjectDefinition) EventListeners() []string {
+	return p.eventListeners
+}
+
+type appListener struct {
+	instance  *ObjectInstance
+	eventType reflect.Type
+	fn        reflect.Value
+}
+
+type eventPublisher struct {
+	locker    sync.RWMutex
+	listeners []*appListener
+}
+
+func (p *eventPublisher) Publish(event interface{}) (err error) {
+
+	if event == nil {
+		return
+	}
+
+	eventVal := reflect.ValueOf(event)
+
+	p.locker.RLock()
+	listeners := p.listeners
+	p.locker.RUnlock()
+
+	for _, listener := range listeners {
+		if !eventVal.Type().AssignableTo(listener.eventType) {
+			continue
+		}
+
+		outs := listener.fn.Call([]reflect.Value{eventVal})
+
+		if len(outs) == 1 && !outs[0].IsNil() {
+			err = ErrEventListenerFailed.New(errors.Params{
+				"name":  listener.instance.definition.Name(),
+				"event": eventVal.Type().String(),
+				"err":   outs[0].Interface(),
+			})
+			return
+		}
+	}
+
+	return
+}
+
+func (p *eventPublisher) PublishAsync(event interface{}) {
+	go p.Publish(event)
+}
+
+// subscribe add the listener methods of the target object of instance, OnEvent
+// of the Listener is subscribed if it has the right signature
+func (p *eventPublisher) subscribe(objIns *ObjectInstance, target interface{}) (err error) {
+
+	def := objIns.definition
+	objVal := reflect.ValueOf(target)
+
+	var listeners []*appListener
+
+	methods := def.EventListeners()
+	if len(methods) == 0 {
+		methods = []string{defaultEventListenerMethod}
+	}
+
+	for _, method := range methods {
+		fn := objVal.MethodByName(method)
+
+		valid := fn.IsValid()
+		if valid {
+			fnType := fn.Type()
+			valid = fnType.NumIn() == 1 && !fnType.IsVariadic() &&
+				(fnType.NumOut() == 0 || (fnType.NumOut() == 1 && fnType.Out(0) == errorType))
+		}
+
+		if !valid {
+			// the method of default name is optional
+			if len(def.EventListeners()) == 0 {
+				continue
+			}
+
+			err = ErrBadEventListener.New(errors.Params{"name": def.Name(), "func": method})
+			return
+		}
+
+		listeners = append(listeners, &appListener{instance: objIns, eventType: fn.Type().In(0), fn: fn})
+	}
+
+	if len(listeners) == 0 {
+		return
+	}
+
+	p.locker.Lock()
+	p.listeners = append(append([]*appListener{}, p.listeners...), listeners...)
+	p.locker.Unlock()
+
+	return
+}
+
+func (p *eventPublisher) unsubscribe(objIns *ObjectInstance) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	var listeners []*appListener
+	for _, listener := range p.listeners {
+		if listener.instance != objIns {
+			listeners = append(listeners, listener)
+		}
+	}
+
+	p.listeners = listeners
+}
+
+func (p *ClassicFactory) Publisher() Publisher {
+	return p.publisher
+}
+
+// injectPublisher set the publisher to the nil exported fields of Publisher type
+func (p *ClassicFactory) injectPublisher(obj interface{}) {
+
+	objVal := reflect.ValueOf(obj)
+	for objVal.Kind() == reflect.Ptr || objVal.Kind() == reflect.Interface {
+		if objVal.IsNil() {
+			return
+		}
+		objVal = objVal.Elem()
+	}
+
+	if objVal.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < objVal.NumField(); i++ {
+		field := objVal.Type().Field(i)
+		if field.PkgPath != "" || field.Type != publisherType {
+			continue
+		}
+
+		if fieldVal := objVal.Field(i); fieldVal.IsNil() && fieldVal.CanSet() {
+			fieldVal.Set(reflect.ValueOf(p.publisher))
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package factory
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+type testOrderPlaced struct {
+	ID string
+}
+
+type testMailer struct {
+	orders []string
+}
+
+func (p *testMailer) OnEvent(event testOrderPlaced) error {
+	if event.ID == "" {
+		return errors.New("empty order id")
+	}
+
+	p.orders = append(p.orders, event.ID)
+	return nil
+}
+
+type testAuditor struct {
+	events int
+}
+
+func (p *testAuditor) Audit(event interface{}) {
+	p.events++
+}
+
+type testShop struct {
+	Publisher Publisher
+}
+
+func init() {
+	RegisterModel((*testMailer)(nil), "testMailer")
+	RegisterModel((*testAuditor)(nil), "testAuditor")
+	RegisterModel((*testShop)(nil), "testShop")
+}
+
+func TestClassicFactoryOfAppEvents(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("mailer", Singleton, "testMailer"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("auditor", Singleton, "testAuditor", DefOptOfEventListener("Audit")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("shop", Prototype, "testShop"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("shop"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	shop := obj.(*testShop)
+	if shop.Publisher == nil {
+		t.Error("publisher should be injected")
+		return
+	}
+
+	if err = shop.Publisher.Publish(testOrderPlaced{ID: "1"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = shop.Publisher.Publish("not an order"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = shop.Publisher.Publish(testOrderPlaced{}); !ErrEventListenerFailed.IsEqual(err) {
+		t.Errorf("the error of listener should be returned, got: %v", err)
+		return
+	}
+
+	if obj, err = factory.GetObject("mailer"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if mailer := obj.(*testMailer); len(mailer.orders) != 1 || mailer.orders[0] != "1" {
+		t.Errorf("mailer should only receive the orders, got: %v", mailer.orders)
+		return
+	}
+
+	if obj, err = factory.GetObject("auditor"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	auditor := obj.(*testAuditor)
+	if auditor.events != 3 {
+		t.Errorf("auditor should receive all events, got: %d", auditor.events)
+		return
+	}
+
+	if err = factory.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Publisher().Publish(testOrderPlaced{ID: "2"}); err != nil || auditor.events != 3 {
+		t.Error("the destroyed listeners should be unsubscribed")
+		return
+	}
+}
+
+func TestClassicFactoryOfAppEventsConcurrentSingleton(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("mailer", Singleton, "testMailer"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := factory.GetObject("mailer"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err = factory.Publisher().Publish(testOrderPlaced{ID: "1"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj, err := factory.GetObject("mailer")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if orders := obj.(*testMailer).orders; len(orders) != 1 {
+		t.Errorf("the event should be delivered once, got: %v", orders)
+		return
+	}
+}
@@ -1,34 +1,89 @@
 package factory
 
 import (
+	"context"
+	"fmt"
 	"github.com/gogap/errors"
 	"github.com/rs/xid"
+	"log/slog"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type ClassicFactory struct {
-	objLocker sync.Mutex
+	objLocker     sync.Mutex
+	insLocker     sync.RWMutex
+	profileLocker sync.RWMutex
+	prepareLocker sync.Mutex
+	rebuildLocker sync.Mutex
 
 	objDefinitions map[string]*ObjectDefinition
 	objAliases     map[string]string
 	objInstances   map[string]*ObjectInstance
+	creationLocker sync.Mutex
+	creationLocks  map[string]*creationLock
+	decorators     map[string][]*decorator
+
+	activeProfiles map[string]bool
+
+	poolLocker     sync.Mutex
+	objPools       map[string]*objectPool
+	borrowedObj    map[interface{}]*objectPool
+	postProcessors []ObjectPostProcessor
+
+	defPostProcessors []DefinitionPostProcessor
+	prepared          bool
+	prepareErr        error
+
+	eventLocker   sync.RWMutex
+	subscriptions []*subscription
+	publisher     *eventPublisher
 
 	modelProvider ModelProvider
+	environment   *Environment
+	metrics       MetricsSink
+	tracer        Tracer
+	logger        *slog.Logger
+	logFilter     func(def *ObjectDefinition) bool
+
+	logSecretPatterns []string
 }
 
-func NewClassicFactory(modelProvider ModelProvider) Factory {
+func NewClassicFactory(modelProvider ModelProvider, opts ...FactoryOption) *ClassicFactory {
 	if modelProvider == nil {
 		modelProvider = defaultModelProvider
 	}
 
-	return &ClassicFactory{
+	fac := &ClassicFactory{
 		objDefinitions: make(map[string]*ObjectDefinition),
 		objAliases:     make(map[string]string),
 		objInstances:   make(map[string]*ObjectInstance),
+		creationLocks:  make(map[string]*creationLock),
+		decorators:     make(map[string][]*decorator),
+		activeProfiles: make(map[string]bool),
+		objPools:       make(map[string]*objectPool),
+		borrowedObj:    make(map[interface{}]*objectPool),
+		publisher:      &eventPublisher{},
 		modelProvider:  modelProvider,
+		environment:    NewEnvironment(),
+		metrics:        nopMetricsSink{},
+		tracer:         nopTracer{},
+
+		logSecretPatterns: DefaultSecretPatterns,
+	}
+
+	for _, opt := range opts {
+		opt.f(fac)
 	}
+
+	return fac
+}
+
+func (p *ClassicFactory) Environment() *Environment {
+	return p.environment
 }
 
 func (p *ClassicFactory) ContainsObject(name string) bool {
@@ -56,18 +111,35 @@ func (p *ClassicFactory) GetAliases(name string) (aliases []string, err error) {
 }
 
 func (p *ClassicFactory) GetObject(name string, opts ...Options) (obj interface{}, err error) {
+	return p.GetObjectCtx(context.Background(), name, opts...)
+}
+
+// GetObjectCtx is the same as GetObject, the ctx is passed to the NewObjectFuncCtx
+// of the definition and its refs, the creation stops when the ctx done
+func (p *ClassicFactory) GetObjectCtx(ctx context.Context, name string, opts ...Options) (obj interface{}, err error) {
 	var def *ObjectDefinition
 
+	if err = p.prepare(); err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			p.publish(Event{Type: ResolutionFailed, Definition: def, Err: err})
+		}
+	}()
+
 	if def, err = p.getObjDefinition(name); err != nil {
+		err = newResolutionError(withResolutionStep(ctx, "", name), err)
 		return
 	}
 
 	var opt Options
-	if opts != nil || len(opts) > 0 {
+	if len(opts) > 0 {
 		opt = opts[0]
 	}
 
-	if obj, err = p.getObject(def, opt); err != nil {
+	if obj, err = p.getObject(withResolutionStep(ctx, "", def.Name()), def, opt); err != nil {
 		return
 	}
 
@@ -91,27 +163,68 @@ func (p *ClassicFactory) IsPrototype(name string) bool {
 	var def *ObjectDefinition
 	var err error
 	if def, err = p.getObjDefinition(name); err != nil {
-		return def.Scope() == Prototype
+		return false
 	}
 
-	return false
+	return def.Scope() == Prototype
 }
 
 func (p *ClassicFactory) IsSingleton(name string) bool {
 	var def *ObjectDefinition
 	var err error
 	if def, err = p.getObjDefinition(name); err != nil {
-		return def.Scope() == Singleton
+		return false
 	}
 
-	return false
+	return def.Scope() == Singleton
 }
 
 func (p *ClassicFactory) IsTypeMatch(name string, typ reflect.Type) bool {
 	var def *ObjectDefinition
 	var err error
 	if def, err = p.getObjDefinition(name); err != nil {
-		return def.IsTypeMatch(typ)
+		return false
+	}
+
+	return def.IsTypeMatch(typ)
+}
+
+func (p *ClassicFactory) SetActiveProfiles(profiles ...string) {
+	activeProfiles := make(map[string]bool)
+	for _, profile := range profiles {
+		activeProfiles[profile] = true
+	}
+
+	p.profileLocker.Lock()
+	p.activeProfiles = activeProfiles
+	p.profileLocker.Unlock()
+}
+
+func (p *ClassicFactory) ActiveProfiles() (profiles []string) {
+	p.profileLocker.RLock()
+	for profile := range p.activeProfiles {
+		profiles = append(profiles, profile)
+	}
+	p.profileLocker.RUnlock()
+
+	sort.Strings(profiles)
+
+	return
+}
+
+// isActive reports whether the definition has no profiles or one of its profiles is active
+func (p *ClassicFactory) isActive(def *ObjectDefinition) bool {
+	if len(def.Profiles()) == 0 {
+		return true
+	}
+
+	p.profileLocker.RLock()
+	defer p.profileLocker.RUnlock()
+
+	for _, profile := range def.Profiles() {
+		if p.activeProfiles[profile] {
+			return true
+		}
 	}
 
 	return false
@@ -137,12 +250,60 @@ func (p *ClassicFactory) Define(
 	model string,
 	opts ...DefinitionOption) (err error) {
 
+	var def *ObjectDefinition
+	if def, err = p.newObjectDefinition(name, scope, model, opts...); err != nil {
+		return
+	}
+
+	p.prepareLocker.Lock()
+
+	if p.prepared {
+		err = p.postProcessDefinitions([]*ObjectDefinition{def})
+	}
+
+	if err == nil {
+		err = p.registerObjectDefinition(def)
+	}
+
+	p.prepareLocker.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	// the listeners could get objects
+	p.definitionRegistered(def)
+
+	return
+}
+
+func (p *ClassicFactory) definitionRegistered(def *ObjectDefinition) {
+	p.publish(Event{Type: DefinitionRegistered, Definition: def})
+
+	p.debug(context.Background(), def, "definition registered",
+		slog.String("scope", def.Scope().String()),
+		slog.String("model", def.Type().String()),
+	)
+}
+
+func (p *ClassicFactory) newObjectDefinition(
+	name string,
+	scope Scope,
+	model string,
+	opts ...DefinitionOption) (def *ObjectDefinition, err error) {
+
 	name = strings.TrimSpace(name)
 	if name == "" {
 		err = ErrEmptyObjectDefinitionName.New()
 		return
 	}
 
+	// the separator is reserved for the instance keys of keyed singleton
+	if strings.Contains(name, keyedInstanceSeparator) {
+		err = ErrBadObjectDefinitionName.New(errors.Params{"name": name, "separator": keyedInstanceSeparator})
+		return
+	}
+
 	model = strings.TrimSpace(model)
 	if model == "" {
 		err = ErrModelNameIsEmpty.New()
@@ -161,19 +322,20 @@ func (p *ClassicFactory) Define(
 		return
 	}
 
-	def := &ObjectDefinition{
-		name:        name,
-		scope:       scope,
-		typ:         typ,
-		refs:        make(map[string]string),
-		refsOptions: make(map[string]Options),
+	def = &ObjectDefinition{
+		name:           name,
+		scope:          scope,
+		typ:            typ,
+		refs:           make(map[string]string),
+		refsOptions:    make(map[string]Options),
+		refsLazy:       make(map[string]reflect.Type),
+		refsCollection: make(map[string]*collectionRef),
+		refsTyped:      make(map[string]*typedRef),
+		refsOptional:   make(map[string]bool),
 	}
 
 	if err = def.options(opts...); err != nil {
-		return
-	}
-
-	if err = p.registerObjectDefinition(def); err != nil {
+		def = nil
 		return
 	}
 
@@ -183,144 +345,595 @@ func (p *ClassicFactory) Define(
 func (p *ClassicFactory) getObjDefinition(name string) (def *ObjectDefinition, err error) {
 	var exist bool
 
-	if def, exist = p.objDefinitions[name]; exist {
-		return
+	p.objLocker.Lock()
+	if def, exist = p.objDefinitions[name]; !exist {
+		var originalName string
+		if originalName, exist = p.objAliases[name]; exist {
+			def, exist = p.objDefinitions[originalName]
+		}
 	}
+	p.objLocker.Unlock()
 
-	var originalName string
-	if originalName, exist = p.objAliases[name]; exist {
-		if def, exist = p.objDefinitions[originalName]; exist {
-			return
-		}
+	if !exist {
+		def = nil
+		err = ErrObjectDefintionNotExist.New(errors.Params{"name": name})
+		return
 	}
 
-	if err = ErrObjectDefintionNotExist.New(errors.Params{"name": name}); err != nil {
+	if !p.isActive(def) {
+		err = ErrObjectDefinitionNotActive.New(errors.Params{"name": name, "profiles": strings.Join(def.Profiles(), ",")})
+		def = nil
 		return
 	}
 
 	return
 }
 
-func (p *ClassicFactory) getObject(def *ObjectDefinition, opts Options) (obj interface{}, err error) {
+// getObject resolve the object, the error is wrapped as ResolutionError with the path in ctx
+func (p *ClassicFactory) getObject(ctx context.Context, def *ObjectDefinition, opts Options) (obj interface{}, err error) {
 
-	var retObj interface{}
+	ctx, span := p.startSpan(ctx, def)
 
-	if def.Scope() == Singleton {
+	defer func() {
+		err = newResolutionError(ctx, err)
 
-		var exist bool
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
 
-		var objIns *ObjectInstance
-		if objIns, exist = p.objInstances[def.Name()]; exist {
-			obj = objIns.Instance()
+	if def.Scope() == Pooled {
+		err = ErrPooledObjectShouldBorrow.New(errors.Params{"name": def.Name()})
+		return
+	}
+
+	return p.resolveObject(ctx, def, opts)
+}
+
+func (p *ClassicFactory) resolveObject(ctx context.Context, def *ObjectDefinition, opts Options) (obj interface{}, err error) {
+
+	instanceKey := def.Name()
+
+	// the creation lock of singleton is released once the instance cached
+	var unlock func()
+	defer func() {
+		if unlock != nil {
+			unlock()
+		}
+	}()
+
+	if def.Scope() == Singleton && !def.IsKeyedSingleton() {
+		var hit bool
+		if obj, hit, unlock = p.cachedSingleton(ctx, def, instanceKey); hit {
 			return
 		}
+	}
+
+	defer func() {
+		if err != nil {
+			p.metrics.IncCounter(MetricCreationFailures, def.Name())
+		}
+	}()
+
+	requested := opts
+
+	opts = MergeOptions(def.DefaultOptions(), opts)
+
+	if opts, err = p.environment.ResolveOptions(opts); err != nil {
+		return
+	}
+
+	if opts, err = decodeOptions(def, opts); err != nil {
+		return
+	}
 
-		// Create new object
-		var newInstanceFn NewObjectFunc
+	p.debug(ctx, def, "effective options", slog.Any("options", MaskSecrets(opts, p.logSecretPatterns)))
 
-		if newInstanceFn, err = p.getNewInstanceFunc(def); err != nil {
+	var optionsKey string
+	if def.Scope() == Singleton && def.IsKeyedSingleton() {
+		if optionsKey, err = hashOptions(opts); err != nil {
 			return
 		}
 
-		if retObj, err = newInstanceFn(opts); err != nil {
+		instanceKey = keyedInstanceKey(def.Name(), optionsKey)
+
+		var hit bool
+		if obj, hit, unlock = p.cachedSingleton(ctx, def, instanceKey); hit {
 			return
 		}
+	}
 
-		p.objInstances[def.Name()] = &ObjectInstance{
-			id:         xid.New().String(),
-			object:     retObj,
-			options:    opts,
-			definition: def,
-		}
+	spanOf(ctx).SetAttributes(Attribute{TraceAttrCached, false})
+
+	// Create new object
+	createStart := time.Now()
+
+	var retObj interface{}
+	retObj, err = p.newObject(ctx, def, opts)
+
+	p.metrics.ObserveDuration(MetricConstructorLatency, def.Name(), time.Since(createStart))
+
+	if err != nil {
+		return
+	}
+
+	p.metrics.IncCounter(MetricInstancesCreated, def.Name())
+
+	objIns := &ObjectInstance{
+		id:         xid.New().String(),
+		key:        optionsKey,
+		object:     retObj,
+		options:    opts,
+		definition: def,
+		requested:  requested,
+	}
+
+	if def.Scope() == Singleton {
+		// Cache the singleton before inject refs, so the refs could ref it back,
+		// the refs and the concurrent resolutions get it before initialized
+		p.cacheSingleton(ctx, def, instanceKey, objIns)
+
+		unlock()
+		unlock = nil
+
+		defer func() {
+			if err != nil {
+				p.cacheSingleton(ctx, def, instanceKey, nil)
+			}
+		}()
 	}
 
-	if def.Scope() == Prototype {
+	p.publish(Event{Type: InstanceCreated, Definition: def, Instance: objIns})
 
-		var newInstanceFn NewObjectFunc
+	injectStart := time.Now()
 
-		if newInstanceFn, err = p.getNewInstanceFunc(def); err != nil {
+	if err = p.injectRefs(ctx, def, retObj, opts); err != nil {
+		return
+	}
+
+	p.injectPublisher(retObj)
+
+	p.metrics.ObserveDuration(MetricInjectionLatency, def.Name(), time.Since(injectStart))
+
+	if retObj, err = p.initObject(def, retObj); err != nil {
+		return
+	}
+
+	if retObj, err = p.decorate(ctx, def, retObj); err != nil {
+		return
+	}
+
+	p.insLocker.Lock()
+	objIns.object = retObj
+	objIns.ready = true
+	p.insLocker.Unlock()
+
+	if def.Scope() == Singleton {
+		target, _ := p.undecorate(def, retObj)
+		if err = p.publisher.subscribe(objIns, target); err != nil {
 			return
 		}
+	}
 
-		if retObj, err = newInstanceFn(opts); err != nil {
-			return
+	p.publish(Event{Type: InstanceInitialized, Definition: def, Instance: objIns})
+
+	obj = retObj
+
+	return
+}
+
+// cachedSingleton returns the cached object of instance key, or holds the creation
+// lock of the key until unlock called, so the concurrent resolutions create only
+// one instance. The lock should be released once the new instance cached, the
+// refs resolved after that may resolve the key again
+func (p *ClassicFactory) cachedSingleton(ctx context.Context, def *ObjectDefinition, instanceKey string) (obj interface{}, hit bool, unlock func()) {
+
+	if obj, hit = p.getSingleton(ctx, def, instanceKey); !hit {
+		unlock = p.lockCreation(instanceKey)
+
+		if obj, hit = p.getSingleton(ctx, def, instanceKey); hit {
+			unlock()
+			unlock = nil
 		}
 	}
 
+	if !hit {
+		p.metrics.IncCounter(MetricSingletonCacheMisses, def.Name())
+		return
+	}
+
+	p.metrics.IncCounter(MetricSingletonCacheHits, def.Name())
+	spanOf(ctx).SetAttributes(Attribute{TraceAttrCached, true})
+
+	return
+}
+
+func (p *ClassicFactory) getSingleton(ctx context.Context, def *ObjectDefinition, instanceKey string) (obj interface{}, exist bool) {
+
+	if scope := rebuildScopeOf(ctx, def); scope != nil {
+		var objIns *ObjectInstance
+		if objIns, exist = scope.get(instanceKey); exist {
+			obj = objIns.object
+		}
+		return
+	}
+
+	p.insLocker.RLock()
+	defer p.insLocker.RUnlock()
+
+	var objIns *ObjectInstance
+	if objIns, exist = p.objInstances[instanceKey]; exist {
+		obj = objIns.object
+	}
+
+	return
+}
+
+// cacheSingleton cache the instance in the rebuild scope of ctx or the factory,
+// the instance key is removed if objIns is nil
+func (p *ClassicFactory) cacheSingleton(ctx context.Context, def *ObjectDefinition, instanceKey string, objIns *ObjectInstance) {
+
+	if scope := rebuildScopeOf(ctx, def); scope != nil {
+		scope.set(instanceKey, objIns)
+		return
+	}
+
+	p.insLocker.Lock()
+	defer p.insLocker.Unlock()
+
+	if objIns == nil {
+		delete(p.objInstances, instanceKey)
+		return
+	}
+
+	p.objInstances[instanceKey] = objIns
+}
+
+type creationLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (p *ClassicFactory) lockCreation(instanceKey string) (unlock func()) {
+	p.creationLocker.Lock()
+	lock, exist := p.creationLocks[instanceKey]
+	if !exist {
+		lock = &creationLock{}
+		p.creationLocks[instanceKey] = lock
+	}
+	lock.refs++
+	p.creationLocker.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		p.creationLocker.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(p.creationLocks, instanceKey)
+		}
+		p.creationLocker.Unlock()
+	}
+}
+
+func (p *ClassicFactory) getInstance(instanceKey string) (objIns *ObjectInstance, exist bool) {
+	p.insLocker.RLock()
+	defer p.insLocker.RUnlock()
+
+	objIns, exist = p.objInstances[instanceKey]
+	return
+}
+
+func (p *ClassicFactory) injectRefs(ctx context.Context, def *ObjectDefinition, retObj interface{}, opts Options) (err error) {
+
+	propagated := def.propagatedOptions(opts)
+
 	// Get ref objects
 	var refObjs = make(map[string]interface{})
+	var refSources = make(map[string]string)
 	for _, fieldName := range def.refsOrder {
 
-		refDefName := def.refs[fieldName]
+		if err = contextError(ctx, def); err != nil {
+			return
+		}
+
+		refOpts := MergeOptions(propagated, def.refsOptions[fieldName])
+
+		if collection, exist := def.refsCollection[fieldName]; exist {
+			var o interface{}
+			if o, err = p.getCollection(withResolutionStep(ctx, fieldName, ""), def, collection, refOpts); err != nil {
+				return
+			}
+
+			refObjs[fieldName] = o
+			refSources[fieldName] = "tag:" + collection.tag
+			continue
+		}
+
+		resolveRef := p.refResolver(def, fieldName)
+
+		if providerType, exist := def.refsLazy[fieldName]; exist {
+			refObjs[fieldName] = p.newProvider(withResolutionStep(ctx, fieldName, ""), providerType, resolveRef, refOpts, def.refsOptional[fieldName]).Interface()
+			refSources[fieldName] = "provider:" + def.refs[fieldName]
+			continue
+		}
 
 		var refDef *ObjectDefinition
-		var exist bool
-		if refDef, exist = p.objDefinitions[refDefName]; !exist {
-			err = ErrObjectDefintionNotExist.New(errors.Params{"name": refDefName})
+		if refDef, err = resolveRef(); err != nil {
+			if def.refsOptional[fieldName] && isMissingDefinitionError(err) {
+				err = nil
+				continue
+			}
+			err = newResolutionError(withResolutionStep(ctx, fieldName, ""), err)
 			return
 		}
 
-		var refOpts Options
-		refOpts, _ = def.refsOptions[fieldName]
-
 		var o interface{}
-		if o, err = p.getObject(refDef, refOpts); err != nil {
+		if o, err = p.getObject(withResolutionStep(ctx, fieldName, refDef.Name()), refDef, refOpts); err != nil {
 			return
 		}
 
 		refObjs[fieldName] = o
+		refSources[fieldName] = refDef.Name()
 	}
 
 	// Inject dependency object
 
 	for _, fieldName := range def.refsOrder {
 
-		fieldValue := refObjs[fieldName]
+		fieldValue, exist := refObjs[fieldName]
+		if !exist {
+			continue
+		}
 
 		if err = p.setStructFieldValue(retObj, fieldName, fieldValue); err != nil {
+			err = newResolutionError(withResolutionStep(ctx, fieldName, ""), err)
 			return
 		}
-	}
 
-	obj = retObj
+		p.debug(ctx, def, "field injected", slog.String("field", fieldName), slog.String("source", refSources[fieldName]))
+	}
 
 	return
 }
 
-func (p *ClassicFactory) getNewInstanceFunc(def *ObjectDefinition) (fn NewObjectFunc, err error) {
+func (p *ClassicFactory) getObjDefinitions() (defs []*ObjectDefinition) {
 	p.objLocker.Lock()
-	defer p.objLocker.Unlock()
+	for _, def := range p.objDefinitions {
+		defs = append(defs, def)
+	}
+	p.objLocker.Unlock()
 
-	fn = def.NewObjectFunc()
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Order() != defs[j].Order() {
+			return defs[i].Order() < defs[j].Order()
+		}
+		return defs[i].Name() < defs[j].Name()
+	})
 
-	if fn != nil {
+	return
+}
+
+func (p *ClassicFactory) refResolver(def *ObjectDefinition, fieldName string) func() (*ObjectDefinition, error) {
+	if typed, exist := def.refsTyped[fieldName]; exist {
+		return func() (*ObjectDefinition, error) {
+			return p.getTypedObjDefinition(typed.typ, typed.qualifier)
+		}
+	}
+
+	refDefName := def.refs[fieldName]
+
+	return func() (*ObjectDefinition, error) {
+		return p.getObjDefinition(refDefName)
+	}
+}
+
+func (p *ClassicFactory) getTypedObjDefinition(typ reflect.Type, qualifier string) (def *ObjectDefinition, err error) {
+
+	var candidates []*ObjectDefinition
+	for _, d := range p.getObjDefinitions() {
+		if !d.isAssignableTo(typ) || !p.isActive(d) {
+			continue
+		}
+
+		if qualifier != "" && d.Qualifier() != qualifier {
+			continue
+		}
+
+		candidates = append(candidates, d)
+	}
+
+	if len(candidates) == 0 {
+		err = ErrNoCandidateDefinition.New(errors.Params{"type": typ.String(), "qualifier": qualifier})
 		return
 	}
 
-	if fn, err = p.newTypeInstance(def.Type()); err != nil {
+	if len(candidates) == 1 {
+		def = candidates[0]
 		return
 	}
 
+	for _, d := range candidates {
+		if !d.IsPrimary() {
+			continue
+		}
+
+		if def != nil {
+			def = nil
+			break
+		}
+
+		def = d
+	}
+
+	if def != nil {
+		return
+	}
+
+	var names []string
+	for _, d := range candidates {
+		names = append(names, fmt.Sprintf("%s (%s::%s)", d.Name(), d.Type().PkgPath(), d.Type().String()))
+	}
+
+	err = ErrAmbiguousDefinitions.New(errors.Params{"type": typ.String(), "qualifier": qualifier, "candidates": strings.Join(names, ", ")})
+
 	return
 }
 
-func (p *ClassicFactory) newTypeInstance(typ reflect.Type) (fn NewObjectFunc, err error) {
+func (p *ClassicFactory) getCollectionDefinitions(def *ObjectDefinition, collection *collectionRef) (defs []*ObjectDefinition) {
+	elemType := collection.typ.Elem()
 
-	val := reflect.New(typ)
+	for _, itemDef := range p.getObjDefinitions() {
 
-	if !val.IsValid() {
+		if itemDef == def || itemDef.Scope() == Pooled || !itemDef.isAssignableTo(elemType) || !p.isActive(itemDef) {
+			continue
+		}
+
+		if collection.tag != "" && !itemDef.HasTag(collection.tag) && itemDef.Qualifier() != collection.tag {
+			continue
+		}
+
+		defs = append(defs, itemDef)
+	}
+
+	return
+}
+
+func (p *ClassicFactory) getCollection(ctx context.Context, def *ObjectDefinition, collection *collectionRef, opts Options) (v interface{}, err error) {
+
+	elemType := collection.typ.Elem()
+
+	var colVal reflect.Value
+	if collection.typ.Kind() == reflect.Map {
+		colVal = reflect.MakeMap(collection.typ)
+	} else {
+		colVal = reflect.MakeSlice(collection.typ, 0, 0)
+	}
+
+	for _, itemDef := range p.getCollectionDefinitions(def, collection) {
+
+		var o interface{}
+		if o, err = p.getObject(withResolutionStep(ctx, "", itemDef.Name()), itemDef, opts); err != nil {
+			return
+		}
+
+		itemVal := reflect.ValueOf(o)
+		if !itemVal.IsValid() || !itemVal.Type().AssignableTo(elemType) {
+			err = ErrRefTypeNotMatch.New(errors.Params{"name": itemDef.Name(), "type": elemType.String()})
+			return
+		}
+
+		if collection.typ.Kind() == reflect.Map {
+			colVal.SetMapIndex(reflect.ValueOf(itemDef.Name()), itemVal)
+		} else {
+			colVal = reflect.Append(colVal, itemVal)
+		}
+	}
+
+	v = colVal.Interface()
+
+	return
+}
+
+func (p *ClassicFactory) getNewInstanceFunc(ctx context.Context, def *ObjectDefinition) (fn NewObjectFuncCtx, err error) {
+	p.objLocker.Lock()
+	fn = def.NewObjectFuncCtx()
+	newObjFunc := def.NewObjectFunc()
+	p.objLocker.Unlock()
+
+	// log after the locker released, the logger could call back into the factory
+	if fn != nil {
+		p.debug(ctx, def, "constructor chosen", slog.String("constructor", "NewObjectFuncCtx"))
+		return
+	}
+
+	if newObjFunc == nil {
+		if newObjFunc, err = p.newTypeInstance(def.Type()); err != nil {
+			return
+		}
+
+		p.debug(ctx, def, "constructor chosen", slog.String("constructor", "newTypeInstance"))
+	} else {
+		p.debug(ctx, def, "constructor chosen", slog.String("constructor", "NewObjectFunc"))
+	}
+
+	fn = func(_ context.Context, opts Options) (interface{}, error) {
+		return newObjFunc(opts)
+	}
+
+	return
+}
+
+func (p *ClassicFactory) newTypeInstance(typ reflect.Type) (fn NewObjectFunc, err error) {
+
+	if !reflect.New(typ).IsValid() {
 		err = ErrReflectValueNotValid.New()
 		return
 	}
 
 	fn = func(_ Options) (v interface{}, err error) {
-		v = val.Interface()
+		v = reflect.New(typ).Interface()
 		return
 	}
 
 	return
 }
 
+// newObject call the new object func of definition, if the ctx could be done,
+// the func runs in a goroutine, and returns an error when the ctx done before the func,
+// the object returned by the func after that is destroyed
+func (p *ClassicFactory) newObject(ctx context.Context, def *ObjectDefinition, opts Options) (obj interface{}, err error) {
+
+	var newInstanceFn NewObjectFuncCtx
+	if newInstanceFn, err = p.getNewInstanceFunc(ctx, def); err != nil {
+		return
+	}
+
+	if timeout := def.CreationTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if ctx.Done() == nil {
+		return newInstanceFn(ctx, opts)
+	}
+
+	if err = contextError(ctx, def); err != nil {
+		return
+	}
+
+	type result struct {
+		obj interface{}
+		err error
+	}
+
+	resultChan := make(chan result, 1)
+
+	go func() {
+		o, e := newInstanceFn(ctx, opts)
+		resultChan <- result{o, e}
+	}()
+
+	select {
+	case r := <-resultChan:
+		obj, err = r.obj, r.err
+	case <-ctx.Done():
+		err = contextError(ctx, def)
+
+		// the constructor may ignore the ctx, the object created late is destroyed
+		go func() {
+			if r := <-resultChan; r.err == nil && r.obj != nil {
+				callObjectFunc(def, r.obj, def.DestroyFuncName(), ErrDestroyFuncNotExist.New, ErrBadDestroyFunc.New)
+			}
+		}()
+	}
+
+	return
+}
+
 func (p *ClassicFactory) setStructFieldValue(v interface{}, fieldName string, fieldValue interface{}) (err error) {
 
 	if v == nil {
@@ -382,9 +995,13 @@ func (p *ClassicFactory) setStructFieldValue(v interface{}, fieldName string, fi
 			return
 		}
 
-		if i+1 >= lenfields && fieldVal.Kind() != reflect.Ptr {
-			err = ErrRefObjectShouldBePtr.New()
-			return
+		if i+1 >= lenfields {
+			switch fieldVal.Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Slice, reflect.Map:
+			default:
+				err = ErrRefObjectShouldBePtr.New()
+				return
+			}
 		}
 
 		val = fieldVal
@@ -392,11 +1009,20 @@ func (p *ClassicFactory) setStructFieldValue(v interface{}, fieldName string, fi
 
 	newVal := reflect.ValueOf(fieldValue)
 
-	if newVal.Kind() == reflect.Ptr {
-		fieldVal.Set(newVal)
-	} else if newVal.Kind() == reflect.Struct {
-		fieldVal.Set(reflect.Indirect(newVal))
+	switch newVal.Kind() {
+	case reflect.Ptr, reflect.Func, reflect.Slice, reflect.Map:
+	case reflect.Struct:
+		newVal = reflect.Indirect(newVal)
+	default:
+		return
 	}
 
+	if !newVal.Type().AssignableTo(fieldVal.Type()) {
+		err = ErrRefTypeNotMatch.New(errors.Params{"name": fieldName, "type": fieldVal.Type().String()})
+		return
+	}
+
+	fieldVal.Set(newVal)
+
 	return
 }
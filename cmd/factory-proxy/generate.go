@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const factoryImportPath = "github.com/gogap/factory"
+
+var (
+	versionElem   = regexp.MustCompile(`^v[0-9]+$`)
+	versionSuffix = regexp.MustCompile(`\.v[0-9]+$`)
+)
+
+type method struct {
+	name    string
+	funcTyp *ast.FuncType
+	file    *ast.File
+}
+
+type proxy struct {
+	iface   string
+	name    string
+	methods []method
+}
+
+type generator struct {
+	pkgName string
+	files   []*ast.File
+	imports map[string]string // path -> name
+	buf     bytes.Buffer
+}
+
+// generate returns the formatted source of the proxies of interfaces declared
+// in the package of dir
+func generate(dir string, typeNames []string) (src []byte, err error) {
+
+	g := &generator{imports: map[string]string{factoryImportPath: "factory"}}
+
+	if err = g.parse(dir); err != nil {
+		return
+	}
+
+	var proxies []*proxy
+	for _, typeName := range typeNames {
+		var p *proxy
+		if p, err = g.proxyOf(strings.TrimSpace(typeName)); err != nil {
+			return
+		}
+		proxies = append(proxies, p)
+	}
+
+	for _, p := range proxies {
+		if err = g.collectImports(p); err != nil {
+			return
+		}
+	}
+
+	g.printf("// Code generated by factory-proxy. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", g.pkgName)
+
+	g.printImports()
+
+	for _, p := range proxies {
+		g.printProxy(p)
+	}
+
+	g.printf("func init() {\n")
+	for _, p := range proxies {
+		g.printf("factory.RegisterProxy((*%s)(nil), func(handler factory.InvocationHandler) interface{} {\n", p.iface)
+		g.printf("return &%s{handler}\n", p.name)
+		g.printf("})\n")
+	}
+	g.printf("}\n")
+
+	if src, err = format.Source(g.buf.Bytes()); err != nil {
+		err = fmt.Errorf("format the generated source: %w", err)
+		return
+	}
+
+	return
+}
+
+func (g *generator) parse(dir string) (err error) {
+
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(dir); err != nil {
+		return
+	}
+
+	fset := token.NewFileSet()
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		var file *ast.File
+		if file, err = parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution); err != nil {
+			return
+		}
+
+		if g.pkgName == "" {
+			g.pkgName = file.Name.Name
+		} else if g.pkgName != file.Name.Name {
+			continue
+		}
+
+		g.files = append(g.files, file)
+	}
+
+	if g.pkgName == "" {
+		err = fmt.Errorf("no go files in %s", dir)
+		return
+	}
+
+	return
+}
+
+func (g *generator) lookupInterface(name string) (iface *ast.InterfaceType, file *ast.File, err error) {
+
+	for _, f := range g.files {
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Name != name {
+					continue
+				}
+
+				if typeSpec.TypeParams != nil {
+					err = fmt.Errorf("generic interface %s is not supported", name)
+					return
+				}
+
+				if iface, ok = typeSpec.Type.(*ast.InterfaceType); !ok {
+					err = fmt.Errorf("%s is not interface", name)
+					return
+				}
+
+				file = f
+				return
+			}
+		}
+	}
+
+	err = fmt.Errorf("interface %s not found in package %s", name, g.pkgName)
+	return
+}
+
+func (g *generator) proxyOf(typeName string) (p *proxy, err error) {
+
+	p = &proxy{
+		iface: typeName,
+		name:  strings.ToLower(typeName[:1]) + typeName[1:] + "Proxy",
+	}
+
+	if p.methods, err = g.methodsOf(typeName, map[string]bool{}); err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	methods := p.methods[:0]
+	for _, m := range p.methods {
+		if m.name == "Invoke" {
+			err = fmt.Errorf("method Invoke of %s conflicts with factory.InvocationHandler", typeName)
+			return
+		}
+		if !seen[m.name] {
+			seen[m.name] = true
+			methods = append(methods, m)
+		}
+	}
+	p.methods = methods
+
+	return
+}
+
+func (g *generator) methodsOf(typeName string, visiting map[string]bool) (methods []method, err error) {
+
+	if visiting[typeName] {
+		err = fmt.Errorf("interface %s embeds itself", typeName)
+		return
+	}
+	visiting[typeName] = true
+	defer delete(visiting, typeName)
+
+	var (
+		iface *ast.InterfaceType
+		file  *ast.File
+	)
+
+	if iface, file, err = g.lookupInterface(typeName); err != nil {
+		return
+	}
+
+	for _, field := range iface.Methods.List {
+
+		if funcTyp, ok := field.Type.(*ast.FuncType); ok {
+			for _, name := range field.Names {
+				methods = append(methods, method{name: name.Name, funcTyp: funcTyp, file: file})
+			}
+			continue
+		}
+
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok {
+			err = fmt.Errorf("embedded %s of %s is not supported, only the interfaces of the same package could be embedded", types.ExprString(field.Type), typeName)
+			return
+		}
+
+		switch ident.Name {
+		case "any":
+		case "error":
+			methods = append(methods, method{
+				name: "Error",
+				funcTyp: &ast.FuncType{
+					Params:  &ast.FieldList{},
+					Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("string")}}},
+				},
+			})
+		default:
+			var embedded []method
+			if embedded, err = g.methodsOf(ident.Name, visiting); err != nil {
+				return
+			}
+			methods = append(methods, embedded...)
+		}
+	}
+
+	return
+}
+
+func importName(spec *ast.ImportSpec) (name, path string) {
+
+	path, _ = strconv.Unquote(spec.Path.Value)
+
+	if spec.Name != nil {
+		name = spec.Name.Name
+		return
+	}
+
+	elems := strings.Split(path, "/")
+	name = elems[len(elems)-1]
+	if len(elems) > 1 && versionElem.MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	name = versionSuffix.ReplaceAllString(strings.TrimPrefix(name, "go-"), "")
+
+	return
+}
+
+func (g *generator) collectImports(p *proxy) (err error) {
+
+	for _, m := range p.methods {
+		if m.file == nil {
+			continue
+		}
+
+		ast.Inspect(m.funcTyp, func(node ast.Node) bool {
+			if err != nil {
+				return false
+			}
+
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			for _, spec := range m.file.Imports {
+				name, path := importName(spec)
+				if name != pkg.Name {
+					continue
+				}
+
+				if exist, ok := g.imports[path]; ok && exist != name {
+					err = fmt.Errorf("import %s of method %s.%s conflicts with %s", path, p.iface, m.name, exist)
+					return false
+				}
+
+				for existPath, existName := range g.imports {
+					if existName == name && existPath != path {
+						err = fmt.Errorf("import name %s of method %s.%s conflicts with %s", name, p.iface, m.name, existPath)
+						return false
+					}
+				}
+
+				g.imports[path] = name
+				return false
+			}
+
+			err = fmt.Errorf("import of %s in method %s.%s not found", pkg.Name, p.iface, m.name)
+			return false
+		})
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) printImports() {
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	g.printf("import (\n")
+	for _, path := range paths {
+		name, _ := importName(&ast.ImportSpec{Path: &ast.BasicLit{Value: strconv.Quote(path)}})
+		if name != g.imports[path] {
+			g.printf("%s ", g.imports[path])
+		}
+		g.printf("%q\n", path)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) printProxy(p *proxy) {
+
+	g.printf("type %s struct {\n", p.name)
+	g.printf("factory.InvocationHandler\n")
+	g.printf("}\n\n")
+
+	for _, m := range p.methods {
+		g.printMethod(p, m)
+	}
+}
+
+func (g *generator) printMethod(p *proxy, m method) {
+
+	used := map[string]bool{"p": true, "results": true}
+
+	uniqueName := func(name, fallback string) string {
+		if name == "" || name == "_" || used[name] {
+			name = fallback
+		}
+		for i := 1; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", fallback, i)
+		}
+		used[name] = true
+		return name
+	}
+
+	var params, args []string
+	if m.funcTyp.Params != nil {
+		for _, field := range m.funcTyp.Params.List {
+			typ := types.ExprString(field.Type)
+
+			names := field.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{nil}
+			}
+
+			for _, ident := range names {
+				name := ""
+				if ident != nil {
+					name = ident.Name
+				}
+
+				name = uniqueName(name, fmt.Sprintf("arg%d", len(args)))
+				params = append(params, name+" "+typ)
+				args = append(args, name)
+			}
+		}
+	}
+
+	var resultTypes, results []string
+	if m.funcTyp.Results != nil {
+		for _, field := range m.funcTyp.Results.List {
+			typ := types.ExprString(field.Type)
+
+			names := field.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{nil}
+			}
+
+			for _, ident := range names {
+				name := ""
+				if ident != nil {
+					name = ident.Name
+				}
+
+				fallback := fmt.Sprintf("r%d", len(results))
+				if typ == "error" {
+					fallback = "err"
+				}
+
+				resultTypes = append(resultTypes, typ)
+				results = append(results, uniqueName(name, fallback))
+			}
+		}
+	}
+
+	g.printf("func (p *%s) %s(%s)", p.name, m.name, strings.Join(params, ", "))
+	switch len(resultTypes) {
+	case 0:
+	case 1:
+		g.printf(" %s", resultTypes[0])
+	default:
+		g.printf(" (%s)", strings.Join(resultTypes, ", "))
+	}
+	g.printf(" {\n")
+
+	invoke := fmt.Sprintf("p.Invoke(%q", m.name)
+	for _, arg := range args {
+		invoke += ", " + arg
+	}
+	invoke += ")"
+
+	if len(results) == 0 {
+		g.printf("%s\n", invoke)
+		g.printf("}\n\n")
+		return
+	}
+
+	g.printf("results := %s\n", invoke)
+	for i, result := range results {
+		g.printf("%s, _ := results[%d].(%s)\n", result, i, resultTypes[i])
+	}
+	g.printf("return %s\n", strings.Join(results, ", "))
+	g.printf("}\n\n")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testEngineSource = `package car
+
+import (
+	"context"
+	"time"
+)
+
+type Starter interface {
+	Start(ctx context.Context) (power int, err error)
+}
+
+type Engine interface {
+	Starter
+	error
+
+	Stop(time.Duration)
+	Tune(name string, values ...float64) error
+}
+
+type Wheel interface {
+	Size() int
+}
+`
+
+const testEngineProxy = `// Code generated by factory-proxy. DO NOT EDIT.
+
+package car
+
+import (
+	"context"
+	"github.com/gogap/factory"
+	"time"
+)
+
+type engineProxy struct {
+	factory.InvocationHandler
+}
+
+func (p *engineProxy) Start(ctx context.Context) (int, error) {
+	results := p.Invoke("Start", ctx)
+	power, _ := results[0].(int)
+	err, _ := results[1].(error)
+	return power, err
+}
+
+func (p *engineProxy) Error() string {
+	results := p.Invoke("Error")
+	r0, _ := results[0].(string)
+	return r0
+}
+
+func (p *engineProxy) Stop(arg0 time.Duration) {
+	p.Invoke("Stop", arg0)
+}
+
+func (p *engineProxy) Tune(name string, values ...float64) error {
+	results := p.Invoke("Tune", name, values)
+	err, _ := results[0].(error)
+	return err
+}
+
+type wheelProxy struct {
+	factory.InvocationHandler
+}
+
+func (p *wheelProxy) Size() int {
+	results := p.Invoke("Size")
+	r0, _ := results[0].(int)
+	return r0
+}
+
+func init() {
+	factory.RegisterProxy((*Engine)(nil), func(handler factory.InvocationHandler) interface{} {
+		return &engineProxy{handler}
+	})
+	factory.RegisterProxy((*Wheel)(nil), func(handler factory.InvocationHandler) interface{} {
+		return &wheelProxy{handler}
+	})
+}
+`
+
+func writeTestPackage(t *testing.T, src string) (dir string) {
+	dir = t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "car.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestGenerate(t *testing.T) {
+
+	dir := writeTestPackage(t, testEngineSource)
+
+	src, err := generate(dir, []string{"Engine", "Wheel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(src) != testEngineProxy {
+		t.Errorf("unexpected proxy source:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+
+	dir := writeTestPackage(t, `package car
+
+type Car struct{}
+
+type Handler interface {
+	Invoke(method string) error
+}
+
+type Remote interface {
+	fmt.Stringer
+}
+`)
+
+	cases := map[string]string{
+		"Engine":  "not found",
+		"Car":     "is not interface",
+		"Handler": "conflicts with factory.InvocationHandler",
+		"Remote":  "is not supported",
+	}
+
+	for typeName, msg := range cases {
+		if _, err := generate(dir, []string{typeName}); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("generate %s should fail with %q, got %v", typeName, msg, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Command factory-proxy generates the proxies of interfaces for the interceptors
+// of factory, each proxy forwards its methods to the embedded InvocationHandler
+// and is registered by RegisterProxy in the init func of generated file:
+//
+//	//go:generate factory-proxy -type Engine,Wheel
+//
+// The interfaces are looked up in the package of the directory, which is the
+// directory of the file with go:generate by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+
+	var (
+		typeNames = flag.String("type", "", "comma separated names of the interfaces, required")
+		output    = flag.String("output", "", "output file name, default <type>_proxy.go")
+		dir       = flag.String("dir", ".", "directory of the package")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: factory-proxy -type Engine[,Wheel] [-output file] [-dir directory]\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	types := strings.Split(*typeNames, ",")
+
+	src, err := generate(*dir, types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "factory-proxy: %s\n", err)
+		os.Exit(1)
+	}
+
+	filename := *output
+	if filename == "" {
+		filename = strings.ToLower(types[0]) + "_proxy.go"
+	}
+
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(*dir, filename)
+	}
+
+	if err = os.WriteFile(filename, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "factory-proxy: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,217 @@
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gogap/errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var errNotConvertible = fmt.Errorf("value not convertible")
+
+// convertValue convert v to typ, it supports the conversions between
+// numbers, strings, bools and durations, and the values decoded from json or yaml
+func convertValue(v interface{}, typ reflect.Type) (ret reflect.Value, err error) {
+
+	if v == nil {
+		err = ErrCouldNotConvertValue.New(errors.Params{"value": v, "type": typ.String()})
+		return
+	}
+
+	val := reflect.ValueOf(v)
+
+	if val.Type().AssignableTo(typ) {
+		ret = val
+		return
+	}
+
+	if num, ok := v.(json.Number); ok {
+		v = string(num)
+		val = reflect.ValueOf(v)
+	}
+
+	ret = reflect.New(typ).Elem()
+
+	defer func() {
+		if err != nil {
+			ret = reflect.Value{}
+			err = ErrCouldNotConvertValue.New(errors.Params{"value": v, "type": typ.String()})
+		}
+	}()
+
+	if typ == durationType {
+		switch val.Kind() {
+		case reflect.String:
+			var d time.Duration
+			if d, err = time.ParseDuration(val.String()); err != nil {
+				return
+			}
+			ret.SetInt(int64(d))
+			return
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		switch val.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			ret.SetString(fmt.Sprint(val.Interface()))
+		default:
+			err = errNotConvertible
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = val.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if val.Uint() > math.MaxInt64 {
+				err = errNotConvertible
+				return
+			}
+			i = int64(val.Uint())
+		case reflect.Float32, reflect.Float64:
+			if val.Float() != math.Trunc(val.Float()) {
+				err = errNotConvertible
+				return
+			}
+			i = int64(val.Float())
+		case reflect.String:
+			if i, err = strconv.ParseInt(strings.TrimSpace(val.String()), 10, 64); err != nil {
+				return
+			}
+		default:
+			err = errNotConvertible
+			return
+		}
+
+		if ret.OverflowInt(i) {
+			err = errNotConvertible
+			return
+		}
+		ret.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if val.Int() < 0 {
+				err = errNotConvertible
+				return
+			}
+			u = uint64(val.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = val.Uint()
+		case reflect.Float32, reflect.Float64:
+			if val.Float() < 0 || val.Float() != math.Trunc(val.Float()) {
+				err = errNotConvertible
+				return
+			}
+			u = uint64(val.Float())
+		case reflect.String:
+			if u, err = strconv.ParseUint(strings.TrimSpace(val.String()), 10, 64); err != nil {
+				return
+			}
+		default:
+			err = errNotConvertible
+			return
+		}
+
+		if ret.OverflowUint(u) {
+			err = errNotConvertible
+			return
+		}
+		ret.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(val.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(val.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = val.Float()
+		case reflect.String:
+			if f, err = strconv.ParseFloat(strings.TrimSpace(val.String()), 64); err != nil {
+				return
+			}
+		default:
+			err = errNotConvertible
+			return
+		}
+		ret.SetFloat(f)
+
+	case reflect.Bool:
+		switch val.Kind() {
+		case reflect.Bool:
+			ret.SetBool(val.Bool())
+		case reflect.String:
+			var b bool
+			if b, err = strconv.ParseBool(strings.TrimSpace(val.String())); err != nil {
+				return
+			}
+			ret.SetBool(b)
+		default:
+			err = errNotConvertible
+		}
+
+	case reflect.Slice:
+		if val.Kind() == reflect.String {
+			var items []interface{}
+			for _, item := range strings.Split(val.String(), ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+			val = reflect.ValueOf(items)
+		}
+
+		if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+			err = errNotConvertible
+			return
+		}
+
+		ret = reflect.MakeSlice(typ, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			var item reflect.Value
+			if item, err = convertValue(val.Index(i).Interface(), typ.Elem()); err != nil {
+				return
+			}
+			ret = reflect.Append(ret, item)
+		}
+
+	case reflect.Map:
+		if val.Kind() != reflect.Map || typ.Key().Kind() != reflect.String || val.Type().Key().Kind() != reflect.String {
+			err = errNotConvertible
+			return
+		}
+
+		ret = reflect.MakeMap(typ)
+		iter := val.MapRange()
+		for iter.Next() {
+			var item reflect.Value
+			if item, err = convertValue(iter.Value().Interface(), typ.Elem()); err != nil {
+				return
+			}
+			ret.SetMapIndex(reflect.ValueOf(iter.Key().String()).Convert(typ.Key()), item)
+		}
+
+	default:
+		if !val.Type().ConvertibleTo(typ) {
+			err = errNotConvertible
+			return
+		}
+		ret = val.Convert(typ)
+	}
+
+	return
+}
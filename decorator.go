@@ -0,0 +1,167 @@
+package factory
+
+import (
+	"context"
+	"github.com/gogap/errors"
+	"reflect"
+)
+
+type decorator struct {
+	def   *ObjectDefinition
+	field string
+}
+
+type decoratorObject struct {
+	def    *ObjectDefinition
+	object interface{}
+}
+
+// DefineDecorator make the objects of target wrapped by the decorator, the original
+// object is injected into the field of decorator. The decorators of a target are
+// stacked in the order defined, so the last one is the outermost. The singletons of
+// target and its dependents are rebuilt, the decorator is removed if rebuild failed
+func (p *ClassicFactory) DefineDecorator(targetName string, decoratorModel string, field string, opts ...DefinitionOption) (err error) {
+
+	var target *ObjectDefinition
+	if target, err = p.lookupObjDefinition(targetName); err != nil {
+		return
+	}
+
+	var def *ObjectDefinition
+	if def, err = p.newObjectDefinition(target.Name()+"@"+decoratorModel, target.Scope(), decoratorModel, opts...); err != nil {
+		return
+	}
+
+	structField, exist := def.Type().FieldByName(field)
+	if !exist || structField.PkgPath != "" {
+		err = ErrDecoratorFieldNotExist.New(errors.Params{"name": def.Name(), "field": field})
+		return
+	}
+
+	// the field receives the target or the outermost decorator defined before
+	decorated := target
+	if decs := p.getDecorators(target.Name()); len(decs) > 0 {
+		decorated = decs[len(decs)-1].def
+	}
+
+	if !reflect.PtrTo(decorated.Type()).AssignableTo(structField.Type) {
+		err = ErrRefTypeNotMatch.New(errors.Params{"name": decorated.Name(), "type": structField.Type.String()})
+		return
+	}
+
+	dec := &decorator{def: def, field: field}
+
+	p.objLocker.Lock()
+	p.decorators[target.Name()] = append(append([]*decorator{}, p.decorators[target.Name()]...), dec)
+	p.objLocker.Unlock()
+
+	if err = p.Refresh(target.Name()); err != nil {
+		p.removeDecorator(target.Name(), dec)
+		return
+	}
+
+	return
+}
+
+func (p *ClassicFactory) removeDecorator(name string, dec *decorator) {
+	p.objLocker.Lock()
+	defer p.objLocker.Unlock()
+
+	var decorators []*decorator
+	for _, d := range p.decorators[name] {
+		if d != dec {
+			decorators = append(decorators, d)
+		}
+	}
+
+	if len(decorators) == 0 {
+		delete(p.decorators, name)
+		return
+	}
+
+	p.decorators[name] = decorators
+}
+
+// removeDecorators remove the decorators of the removed definition, after its instances destroyed
+func (p *ClassicFactory) removeDecorators(name string) {
+	p.objLocker.Lock()
+	delete(p.decorators, name)
+	p.objLocker.Unlock()
+}
+
+func (p *ClassicFactory) getDecorators(name string) []*decorator {
+	p.objLocker.Lock()
+	defer p.objLocker.Unlock()
+
+	return p.decorators[name]
+}
+
+// decorate wrap the initialized object by the decorators of def in order
+func (p *ClassicFactory) decorate(ctx context.Context, def *ObjectDefinition, obj interface{}) (retObj interface{}, err error) {
+
+	retObj = obj
+
+	for _, decorator := range p.getDecorators(def.Name()) {
+
+		decCtx := withResolutionStep(ctx, "", decorator.def.Name())
+
+		var opts Options
+		if opts, err = p.environment.ResolveOptions(decorator.def.DefaultOptions()); err != nil {
+			return
+		}
+
+		if opts, err = decodeOptions(decorator.def, opts); err != nil {
+			return
+		}
+
+		var decObj interface{}
+		if decObj, err = p.newObject(decCtx, decorator.def, opts); err != nil {
+			return
+		}
+
+		if err = p.setStructFieldValue(decObj, decorator.field, retObj); err != nil {
+			err = newResolutionError(withResolutionStep(decCtx, decorator.field, ""), err)
+			return
+		}
+
+		if err = p.injectRefs(decCtx, decorator.def, decObj, opts); err != nil {
+			return
+		}
+
+		p.injectPublisher(decObj)
+
+		if decObj, err = p.initObject(decorator.def, decObj); err != nil {
+			return
+		}
+
+		retObj = decObj
+	}
+
+	return
+}
+
+// undecorate returns the original object of target, and the decorator objects
+// from the outermost, the decorators defined after the object created are skipped
+func (p *ClassicFactory) undecorate(def *ObjectDefinition, obj interface{}) (target interface{}, decorators []decoratorObject) {
+
+	target = ProxyTarget(obj)
+
+	decs := p.getDecorators(def.Name())
+
+	for i := len(decs) - 1; i >= 0; i-- {
+		objVal := reflect.ValueOf(target)
+		for objVal.Kind() == reflect.Ptr && !objVal.IsNil() {
+			objVal = objVal.Elem()
+		}
+
+		if objVal.Kind() != reflect.Struct || objVal.Type() != decs[i].def.Type() {
+			continue
+		}
+
+		decorators = append(decorators, decoratorObject{def: decs[i].def, object: target})
+
+		target = ProxyTarget(objVal.FieldByName(decs[i].field).Interface())
+	}
+
+	return
+}
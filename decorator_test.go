@@ -0,0 +1,224 @@
+package factory
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testStore interface {
+	Get(key string) string
+}
+
+type testMemStore struct {
+	gets   int
+	events *[]string
+}
+
+func (p *testMemStore) Get(key string) string {
+	p.gets++
+	return "value of " + key
+}
+
+func (p *testMemStore) Close() {
+	*p.events = append(*p.events, "close store")
+}
+
+type testCachingStore struct {
+	Store testStore
+	cache map[string]string
+}
+
+func (p *testCachingStore) Get(key string) string {
+	if p.cache == nil {
+		p.cache = make(map[string]string)
+	}
+
+	if value, exist := p.cache[key]; exist {
+		return value
+	}
+
+	value := p.Store.Get(key)
+	p.cache[key] = value
+
+	return value
+}
+
+type testTracingStore struct {
+	Store  testStore
+	Prefix string
+}
+
+func (p *testTracingStore) Get(key string) string {
+	return p.Prefix + p.Store.Get(key)
+}
+
+type testStoreConsumer struct {
+	Store testStore
+}
+
+func init() {
+	RegisterModel((*testMemStore)(nil), "testMemStore")
+	RegisterModel((*testCachingStore)(nil), "testCachingStore")
+	RegisterModel((*testTracingStore)(nil), "testTracingStore")
+	RegisterModel((*testStoreConsumer)(nil), "testStoreConsumer")
+}
+
+func TestClassicFactoryOfDecorator(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var events []string
+
+	if err = factory.Define("store", Singleton, "testMemStore",
+		DefOptOfNewObjectFunc(func(opts Options) (interface{}, error) {
+			return &testMemStore{events: &events}, nil
+		}),
+		DefOptOfDestroyFunc("Close"),
+	); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("consumer", Singleton, "testStoreConsumer", DefOptOfObjectRef("Store", "store")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testCachingStore", "Store"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testTracingStore", "Store",
+		DefOptOfDefaultOptions(Options{"prefix": "traced "}),
+		DefOptOfNewObjectFunc(func(opts Options) (interface{}, error) {
+			prefix, err := opts.GetString("prefix")
+			return &testTracingStore{Prefix: prefix}, err
+		}),
+	); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the store rebuilt for consumer after the first decorator is destroyed again
+	if strings.Join(events, ",") != "close store,close store" {
+		t.Errorf("the existing stores should be destroyed, got: %v", events)
+		return
+	}
+
+	obj, err := factory.GetObject("consumer")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	store := obj.(*testStoreConsumer).Store
+
+	tracing, ok := store.(*testTracingStore)
+	if !ok {
+		t.Errorf("the outermost should be the last decorator, got: %T", store)
+		return
+	}
+
+	if _, ok = tracing.Store.(*testCachingStore); !ok {
+		t.Errorf("the tracing store should wrap the caching store, got: %T", tracing.Store)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		if value := store.Get("a"); value != "traced value of a" {
+			t.Errorf("unexpected value: %s", value)
+			return
+		}
+	}
+
+	if memStore := tracing.Store.(*testCachingStore).Store.(*testMemStore); memStore.gets != 1 {
+		t.Errorf("the store should be cached, got gets: %d", memStore.gets)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testCachingStore", "Store",
+		DefOptOfNewObjectFunc(func(opts Options) (interface{}, error) {
+			return nil, errors.New("bad decorator")
+		}),
+	); err == nil {
+		t.Error("the decorator failed to build should fail")
+		return
+	}
+
+	if obj, err = factory.GetObject("consumer"); err != nil || obj.(*testStoreConsumer).Store != store || len(events) != 2 {
+		t.Errorf("the instances should be rolled back, got: %v, events: %v", err, events)
+		return
+	}
+
+	if _, err = factory.GetObject("store"); err != nil {
+		t.Errorf("the failed decorator should be removed, got: %v", err)
+		return
+	}
+
+	if err = factory.DestroySingleton("store"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(events) != 3 {
+		t.Errorf("the decorated store should be destroyed, got: %v", events)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testFeatureConsumer", "Feature"); !ErrRefTypeNotMatch.IsEqual(err) {
+		t.Errorf("decorator of field not assignable from the decorated should fail, got: %v", err)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testCachingStore", "NotExist"); !ErrDecoratorFieldNotExist.IsEqual(err) {
+		t.Errorf("decorator of not exist field should fail, got: %v", err)
+		return
+	}
+}
+
+func TestClassicFactoryOfDecoratorRemoved(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("store", Singleton, "testMemStore"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.DefineDecorator("store", "testCachingStore", "Store"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.RemoveDefinition("store", false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("store", Singleton, "testDestroyObject"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj, err := factory.GetObject("store")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, ok := obj.(*testDestroyObject); !ok {
+		t.Errorf("the decorators of removed definition should be removed, got: %T", obj)
+		return
+	}
+}
@@ -0,0 +1,168 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gogap/errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefinitionSpec is the definition loaded from file, the objects are created
+// by the type of model, the constructors could be set by definition post processors
+type DefinitionSpec struct {
+	Name        string            `json:"name"`
+	Scope       string            `json:"scope,omitempty"`
+	Model       string            `json:"model"`
+	Refs        map[string]string `json:"refs,omitempty"`
+	Options     Options           `json:"options,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Profiles    []string          `json:"profiles,omitempty"`
+	Order       int               `json:"order,omitempty"`
+	Qualifier   string            `json:"qualifier,omitempty"`
+	Primary     bool              `json:"primary,omitempty"`
+	LazyInit    bool              `json:"lazy_init,omitempty"`
+	InitialFunc string            `json:"initial_func,omitempty"`
+	DestroyFunc string            `json:"destroy_func,omitempty"`
+}
+
+// LoadDefinitionFile load the json array of definition specs
+func LoadDefinitionFile(filename string) (specs []DefinitionSpec, err error) {
+
+	var data []byte
+	if data, err = os.ReadFile(filename); err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(&specs); err != nil {
+		err = ErrBadDefinitionFile.New(errors.Params{"file": filename, "err": err})
+		return
+	}
+
+	names := make(map[string]bool)
+	for _, spec := range specs {
+		if names[spec.Name] {
+			err = ErrBadDefinitionFile.New(errors.Params{"file": filename, "err": "duplicate definition " + spec.Name})
+			return
+		}
+		names[spec.Name] = true
+	}
+
+	return
+}
+
+// ParseScope parse the scope of singleton, prototype or pooled, the empty is singleton
+func ParseScope(s string) (scope Scope, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "singleton":
+		scope = Singleton
+	case "prototype":
+		scope = Prototype
+	case "pooled":
+		scope = Pooled
+	default:
+		err = ErrUnknownScope.New(errors.Params{"scope": s})
+	}
+
+	return
+}
+
+func (p DefinitionSpec) Equal(spec DefinitionSpec) bool {
+	return reflect.DeepEqual(p, spec)
+}
+
+// Change convert the spec to the definition change of ApplyDefinitions
+func (p DefinitionSpec) Change() (change DefinitionChange, err error) {
+
+	var scope Scope
+	if scope, err = ParseScope(p.Scope); err != nil {
+		return
+	}
+
+	var opts []DefinitionOption
+
+	var fields []string
+	for field := range p.Refs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		opts = append(opts, DefOptOfObjectRef(field, p.Refs[field]))
+	}
+
+	if p.Options != nil {
+		opts = append(opts, DefOptOfDefaultOptions(p.Options))
+	}
+
+	if len(p.Tags) > 0 {
+		opts = append(opts, DefOptOfTags(p.Tags...))
+	}
+
+	if len(p.Profiles) > 0 {
+		opts = append(opts, DefOptOfProfiles(p.Profiles...))
+	}
+
+	if p.Qualifier != "" {
+		opts = append(opts, DefOptOfQualifier(p.Qualifier))
+	}
+
+	if p.Primary {
+		opts = append(opts, DefOptOfPrimary())
+	}
+
+	if p.LazyInit {
+		opts = append(opts, DefOptOfLazyInit())
+	}
+
+	opts = append(opts,
+		DefOptOfOrder(p.Order),
+		DefOptOfInitialFunc(p.InitialFunc),
+		DefOptOfDestroyFunc(p.DestroyFunc),
+	)
+
+	change = DefinitionChange{
+		Name:    p.Name,
+		Scope:   scope,
+		Model:   p.Model,
+		Options: opts,
+	}
+
+	return
+}
+
+// DiffDefinitionSpecs returns the names of definitions added, changed or removed
+// in the new specs compared with the old ones
+func DiffDefinitionSpecs(oldSpecs, newSpecs []DefinitionSpec) (added, changed, removed []string) {
+
+	olds := make(map[string]DefinitionSpec, len(oldSpecs))
+	for _, spec := range oldSpecs {
+		olds[spec.Name] = spec
+	}
+
+	news := make(map[string]bool, len(newSpecs))
+	for _, spec := range newSpecs {
+		news[spec.Name] = true
+
+		oldSpec, exist := olds[spec.Name]
+		switch {
+		case !exist:
+			added = append(added, spec.Name)
+		case !oldSpec.Equal(spec):
+			changed = append(changed, spec.Name)
+		}
+	}
+
+	for _, spec := range oldSpecs {
+		if !news[spec.Name] {
+			removed = append(removed, spec.Name)
+		}
+	}
+
+	return
+}
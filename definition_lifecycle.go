@@ -0,0 +1,626 @@
+package factory
+
+import (
+	"context"
+	"github.com/gogap/errors"
+	"strings"
+	"sync"
+)
+
+// RemoveDefinition unregister the definition and destroy its cached instances,
+// it fails if other definitions still ref it, unless cascade, then the dependents
+// are removed too
+func (p *ClassicFactory) RemoveDefinition(name string, cascade bool) (err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.lookupObjDefinition(name); err != nil {
+		return
+	}
+
+	dependents := p.dependentsOf(def)
+
+	if len(dependents) > 0 && !cascade {
+		var names []string
+		for _, dependent := range dependents {
+			names = append(names, dependent.Name())
+		}
+
+		err = ErrDefinitionStillReferenced.New(errors.Params{"name": def.Name(), "refs": strings.Join(names, ",")})
+		return
+	}
+
+	// remove the deepest dependents first
+	removing := append(dependents, def)
+
+	for i := len(removing) - 1; i >= 0; i-- {
+		p.objLocker.Lock()
+		delete(p.objDefinitions, removing[i].Name())
+		p.objLocker.Unlock()
+
+		if destroyErr := p.destroyInstances(removing[i]); destroyErr != nil && err == nil {
+			err = destroyErr
+		}
+
+		p.removeDecorators(removing[i].Name())
+	}
+
+	return
+}
+
+// DefinitionChange define or redefine the definition of Name, or remove it if Remove
+type DefinitionChange struct {
+	Name    string
+	Remove  bool
+	Scope   Scope
+	Model   string
+	Options []DefinitionOption
+}
+
+// Redefine replace the registered definition of the name, the singletons of it
+// and its dependents which were created are rebuilt
+func (p *ClassicFactory) Redefine(
+	name string,
+	scope Scope,
+	model string,
+	opts ...DefinitionOption) (err error) {
+
+	if _, err = p.lookupObjDefinition(name); err != nil {
+		return
+	}
+
+	return p.ApplyDefinitions(DefinitionChange{Name: name, Scope: scope, Model: model, Options: opts})
+}
+
+// Refresh rebuild the created singletons of the definition and its dependents
+// with the same options
+func (p *ClassicFactory) Refresh(name string) (err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.lookupObjDefinition(name); err != nil {
+		return
+	}
+
+	names := []string{def.Name()}
+	for _, dependent := range p.dependentsOf(def) {
+		names = append(names, dependent.Name())
+	}
+
+	p.rebuildLocker.Lock()
+	defer p.rebuildLocker.Unlock()
+
+	return p.rebuild(nil, names)
+}
+
+// DestroySingleton destroy the cached instances of the singleton, they will be
+// created again by the next GetObject
+func (p *ClassicFactory) DestroySingleton(name string) (err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.lookupObjDefinition(name); err != nil {
+		return
+	}
+
+	if def.Scope() != Singleton {
+		err = ErrObjectIsNotSingleton.New(errors.Params{"name": name})
+		return
+	}
+
+	return p.destroyInstances(def)
+}
+
+// ApplyDefinitions apply the changes atomically, the created singletons of the changed
+// definitions and their dependents are rebuilt, the old instances are destroyed after
+// all the new ones initialized, otherwise the definitions and instances are rolled back
+func (p *ClassicFactory) ApplyDefinitions(changes ...DefinitionChange) (err error) {
+
+	p.rebuildLocker.Lock()
+	defer p.rebuildLocker.Unlock()
+
+	// the definitions are post processed and swapped under the prepare locker,
+	// it is released before rebuild, the user code may get objects in rebuild
+	p.prepareLocker.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			p.prepareLocker.Unlock()
+		}
+	}()
+
+	var newDefs []*ObjectDefinition
+	removed := make(map[string]bool)
+
+	for _, change := range changes {
+		if change.Remove {
+			if _, err = p.lookupObjDefinition(change.Name); err != nil {
+				return
+			}
+
+			removed[strings.TrimSpace(change.Name)] = true
+			continue
+		}
+
+		var def *ObjectDefinition
+		if def, err = p.newObjectDefinition(change.Name, change.Scope, change.Model, change.Options...); err != nil {
+			return
+		}
+
+		newDefs = append(newDefs, def)
+	}
+
+	if p.prepared {
+		if err = p.postProcessDefinitions(newDefs); err != nil {
+			return
+		}
+	}
+
+	var names []string
+
+	for _, def := range newDefs {
+		names = append(names, def.Name())
+
+		if oldDef, lookupErr := p.lookupObjDefinition(def.Name()); lookupErr == nil {
+			for _, dependent := range p.dependentsOf(oldDef) {
+				names = append(names, dependent.Name())
+			}
+		}
+	}
+
+	for name := range removed {
+		oldDef, _ := p.lookupObjDefinition(name)
+
+		for _, dependent := range p.dependentsOf(oldDef) {
+			if !removed[dependent.Name()] {
+				err = ErrDefinitionStillReferenced.New(errors.Params{"name": name, "refs": dependent.Name()})
+				return
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	p.objLocker.Lock()
+
+	previous := make(map[string]*ObjectDefinition)
+
+	for name := range removed {
+		previous[name] = p.objDefinitions[name]
+		delete(p.objDefinitions, name)
+	}
+
+	for _, def := range newDefs {
+		previous[def.Name()] = p.objDefinitions[def.Name()]
+		p.objDefinitions[def.Name()] = def
+	}
+
+	p.objLocker.Unlock()
+
+	// typed refs and collections may match the new definitions
+	for _, def := range newDefs {
+		for _, dependent := range p.dependentsOf(def) {
+			names = append(names, dependent.Name())
+		}
+	}
+
+	p.prepareLocker.Unlock()
+	locked = false
+
+	// the listeners could get objects
+	for _, def := range newDefs {
+		p.definitionRegistered(def)
+	}
+
+	if err = p.rebuild(previous, names); err != nil {
+		return
+	}
+
+	for name := range removed {
+		p.removeDecorators(name)
+	}
+
+	return
+}
+
+// rebuild create the singletons of names which were created again, the new
+// instances are cached in the rebuild scope until all of them initialized, then
+// they replace the old instances which are destroyed. Otherwise the new ones are
+// destroyed, and the definitions are restored to previous if it is not nil, the
+// nil definition of previous is removed
+func (p *ClassicFactory) rebuild(previous map[string]*ObjectDefinition, names []string) (err error) {
+
+	old := p.instancesOf(names...)
+
+	scope := newRebuildScope(names)
+	ctx := withRebuildScope(context.Background(), scope)
+
+	for _, objIns := range old.instances {
+		if objIns.definition.Scope() != Singleton || objIns.Key() != "" {
+			continue
+		}
+
+		var def *ObjectDefinition
+		if def, err = p.lookupObjDefinition(objIns.definition.Name()); err != nil {
+			// the definition was removed
+			err = nil
+			continue
+		}
+
+		if _, exist := scope.get(def.Name()); exist {
+			continue
+		}
+
+		if _, err = p.getObject(withResolutionStep(ctx, "", def.Name()), def, objIns.requested); err != nil {
+			break
+		}
+	}
+
+	replacements := scope.close()
+
+	if err != nil {
+		if previous != nil {
+			p.objLocker.Lock()
+			for name, def := range previous {
+				if def == nil {
+					delete(p.objDefinitions, name)
+				} else {
+					p.objDefinitions[name] = def
+				}
+			}
+			p.objLocker.Unlock()
+		}
+
+		created := &detachedInstances{}
+		for _, objIns := range replacements {
+			created.instances = append(created.instances, objIns)
+		}
+
+		p.destroy(created)
+
+		return
+	}
+
+	return p.destroy(p.swapInstances(old, replacements))
+}
+
+// lookupObjDefinition get the registered definition even it is not active
+func (p *ClassicFactory) lookupObjDefinition(name string) (def *ObjectDefinition, err error) {
+	p.objLocker.Lock()
+	defer p.objLocker.Unlock()
+
+	var exist bool
+	if def, exist = p.objDefinitions[strings.TrimSpace(name)]; !exist {
+		def = nil
+		err = ErrObjectDefintionNotExist.New(errors.Params{"name": name})
+		return
+	}
+
+	return
+}
+
+// dependentsOf returns the definitions which ref the definition directly or
+// indirectly, the nearer dependents come first
+func (p *ClassicFactory) dependentsOf(def *ObjectDefinition) (dependents []*ObjectDefinition) {
+
+	defs := p.getObjDefinitions()
+
+	visited := map[*ObjectDefinition]bool{def: true}
+	queue := []*ObjectDefinition{def}
+
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+
+		for _, d := range defs {
+			if visited[d] || !p.isRefTo(d, target) {
+				continue
+			}
+
+			visited[d] = true
+			dependents = append(dependents, d)
+			queue = append(queue, d)
+		}
+	}
+
+	return
+}
+
+// destroyOrder returns all the definitions in reverse dependency order, so each
+// definition comes after its dependents
+func (p *ClassicFactory) destroyOrder() (ordered []*ObjectDefinition) {
+
+	visited := map[*ObjectDefinition]bool{}
+
+	var visit func(def *ObjectDefinition)
+	visit = func(def *ObjectDefinition) {
+		if visited[def] {
+			return
+		}
+
+		visited[def] = true
+
+		for _, dependent := range p.dependentsOf(def) {
+			visit(dependent)
+		}
+
+		ordered = append(ordered, def)
+	}
+
+	for _, def := range p.getObjDefinitions() {
+		visit(def)
+	}
+
+	return
+}
+
+// isRefTo reports whether the definition refs the target by name, type or collection
+func (p *ClassicFactory) isRefTo(def, target *ObjectDefinition) bool {
+	for _, fieldName := range def.refsOrder {
+
+		if collection, exist := def.refsCollection[fieldName]; exist {
+			for _, member := range p.getCollectionDefinitions(def, collection) {
+				if member.Name() == target.Name() {
+					return true
+				}
+			}
+			continue
+		}
+
+		if def.refs[fieldName] == target.Name() {
+			return true
+		}
+
+		if refDef, err := p.refResolver(def, fieldName)(); err == nil && refDef.Name() == target.Name() {
+			return true
+		}
+	}
+
+	for _, decorator := range p.getDecorators(def.Name()) {
+		if p.isRefTo(decorator.def, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type detachedInstances struct {
+	instances []*ObjectInstance
+	pools     []*objectPool
+}
+
+// detachInstances remove the cached instances and the pools of the definitions
+// from the factory, they could be destroyed or attached back
+func (p *ClassicFactory) detachInstances(names ...string) (detached *detachedInstances) {
+
+	detached = &detachedInstances{}
+
+	p.insLocker.Lock()
+	for instanceKey, objIns := range p.objInstances {
+		for _, name := range names {
+			if instanceKey == name || strings.HasPrefix(instanceKey, keyedInstanceKey(name, "")) {
+				detached.instances = append(detached.instances, objIns)
+				delete(p.objInstances, instanceKey)
+				break
+			}
+		}
+	}
+	p.insLocker.Unlock()
+
+	p.poolLocker.Lock()
+	for _, name := range names {
+		if pool, exist := p.objPools[name]; exist {
+			detached.pools = append(detached.pools, pool)
+			delete(p.objPools, name)
+		}
+	}
+	p.poolLocker.Unlock()
+
+	return
+}
+
+// detachInstanceKeys remove the cached instances of the instance keys
+func (p *ClassicFactory) detachInstanceKeys(instanceKeys ...string) (detached *detachedInstances) {
+
+	detached = &detachedInstances{}
+
+	p.insLocker.Lock()
+	for _, instanceKey := range instanceKeys {
+		if objIns, exist := p.objInstances[instanceKey]; exist {
+			detached.instances = append(detached.instances, objIns)
+			delete(p.objInstances, instanceKey)
+		}
+	}
+	p.insLocker.Unlock()
+
+	return
+}
+
+// instancesOf returns the cached instances and the pools of the definitions,
+// they are kept in the factory
+func (p *ClassicFactory) instancesOf(names ...string) (instances *detachedInstances) {
+
+	instances = &detachedInstances{}
+
+	p.insLocker.RLock()
+	for instanceKey, objIns := range p.objInstances {
+		for _, name := range names {
+			if instanceKey == name || strings.HasPrefix(instanceKey, keyedInstanceKey(name, "")) {
+				instances.instances = append(instances.instances, objIns)
+				break
+			}
+		}
+	}
+	p.insLocker.RUnlock()
+
+	p.poolLocker.Lock()
+	for _, name := range names {
+		if pool, exist := p.objPools[name]; exist {
+			instances.pools = append(instances.pools, pool)
+		}
+	}
+	p.poolLocker.Unlock()
+
+	return
+}
+
+// swapInstances cache the replacements and detach the old instances and pools
+// which are still in the factory, the instances cached after old collected are kept
+func (p *ClassicFactory) swapInstances(old *detachedInstances, replacements map[string]*ObjectInstance) (detached *detachedInstances) {
+
+	detached = &detachedInstances{}
+
+	p.insLocker.Lock()
+	for _, objIns := range old.instances {
+		instanceKey := objIns.instanceKey()
+		if p.objInstances[instanceKey] == objIns {
+			detached.instances = append(detached.instances, objIns)
+			delete(p.objInstances, instanceKey)
+		}
+	}
+
+	for instanceKey, objIns := range replacements {
+		p.objInstances[instanceKey] = objIns
+	}
+	p.insLocker.Unlock()
+
+	p.poolLocker.Lock()
+	for _, pool := range old.pools {
+		if p.objPools[pool.def.Name()] == pool {
+			detached.pools = append(detached.pools, pool)
+			delete(p.objPools, pool.def.Name())
+		}
+	}
+	p.poolLocker.Unlock()
+
+	return
+}
+
+// destroy call the destroy func of the detached instances and the idle objects of the pools
+func (p *ClassicFactory) destroy(detached *detachedInstances) (err error) {
+
+	destroy := func(objIns *ObjectInstance) {
+		def := objIns.definition
+
+		p.publisher.unsubscribe(objIns)
+
+		// the decorators are destroyed from the outermost, before the target
+		target, decorators := p.undecorate(def, objIns.Instance())
+
+		var destroyErr error
+		for _, decorator := range decorators {
+			decErr := callObjectFunc(decorator.def, decorator.object, decorator.def.DestroyFuncName(), ErrDestroyFuncNotExist.New, ErrBadDestroyFunc.New)
+			if decErr != nil && destroyErr == nil {
+				destroyErr = decErr
+			}
+		}
+
+		if targetErr := callObjectFunc(def, target, def.DestroyFuncName(), ErrDestroyFuncNotExist.New, ErrBadDestroyFunc.New); targetErr != nil && destroyErr == nil {
+			destroyErr = targetErr
+		}
+
+		if destroyErr != nil && err == nil {
+			err = destroyErr
+		}
+
+		p.publish(Event{Type: InstanceDestroyed, Definition: def, Instance: objIns, Err: destroyErr})
+	}
+
+	for _, objIns := range detached.instances {
+		destroy(objIns)
+	}
+
+	for _, pool := range detached.pools {
+		pool.locker.Lock()
+		idle := pool.idle
+		pool.idle = nil
+		pool.locker.Unlock()
+
+		for _, item := range idle {
+			destroy(&ObjectInstance{object: item.object, definition: pool.def})
+		}
+	}
+
+	return
+}
+
+// destroyInstances remove the cached instances and the pool of the definition,
+// and call the destroy func of them
+func (p *ClassicFactory) destroyInstances(def *ObjectDefinition) (err error) {
+	return p.destroy(p.detachInstances(def.Name()))
+}
+
+type rebuildScopeKey struct{}
+
+// rebuildScope caches the singletons of the definitions in rebuild, until closed
+type rebuildScope struct {
+	names map[string]bool
+
+	locker    sync.Mutex
+	closed    bool
+	instances map[string]*ObjectInstance
+}
+
+func newRebuildScope(names []string) *rebuildScope {
+	scope := &rebuildScope{
+		names:     make(map[string]bool),
+		instances: make(map[string]*ObjectInstance),
+	}
+
+	for _, name := range names {
+		scope.names[name] = true
+	}
+
+	return scope
+}
+
+func withRebuildScope(ctx context.Context, scope *rebuildScope) context.Context {
+	return context.WithValue(ctx, rebuildScopeKey{}, scope)
+}
+
+// rebuildScopeOf returns the scope caching the singletons of def, it is nil if
+// they are cached in the factory, such as the scope closed but the ctx is kept
+// by the lazy providers
+func rebuildScopeOf(ctx context.Context, def *ObjectDefinition) *rebuildScope {
+	scope, _ := ctx.Value(rebuildScopeKey{}).(*rebuildScope)
+	if scope == nil || !scope.names[def.Name()] {
+		return nil
+	}
+
+	scope.locker.Lock()
+	defer scope.locker.Unlock()
+
+	if scope.closed {
+		return nil
+	}
+
+	return scope
+}
+
+func (p *rebuildScope) get(instanceKey string) (objIns *ObjectInstance, exist bool) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	objIns, exist = p.instances[instanceKey]
+	return
+}
+
+func (p *rebuildScope) set(instanceKey string, objIns *ObjectInstance) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if objIns == nil {
+		delete(p.instances, instanceKey)
+		return
+	}
+
+	p.instances[instanceKey] = objIns
+}
+
+// close returns the cached instances, the later resolutions use the factory
+func (p *rebuildScope) close() map[string]*ObjectInstance {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	p.closed = true
+
+	return p.instances
+}
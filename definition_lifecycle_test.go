@@ -0,0 +1,286 @@
+package factory
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testDestroyObject struct {
+	BValue    string
+	destroyed bool
+	events    int
+}
+
+func (p *testDestroyObject) Close() {
+	p.destroyed = true
+}
+
+func (p *testDestroyObject) OnEvent(event testOrderPlaced) error {
+	p.events++
+	return nil
+}
+
+type testFeatureConsumer struct {
+	Feature *testDestroyObject
+}
+
+func init() {
+	RegisterModel((*testDestroyObject)(nil), "testDestroyObject")
+	RegisterModel((*testFeatureConsumer)(nil), "testFeatureConsumer")
+}
+
+func TestClassicFactoryRemoveDefinition(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("testObjBName", Singleton, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Singleton, "testObject", DefOptOfObjectRef("ObjB", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("testObjName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.RemoveDefinition("testObjBName", false); !ErrDefinitionStillReferenced.IsEqual(err) {
+		t.Errorf("removing the referenced definition should fail, got: %v", err)
+		return
+	}
+
+	if err = factory.RemoveDefinition("testObjBName", true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if factory.ContainsObject("testObjBName") || factory.ContainsObject("testObjName") {
+		t.Error("the definition and its dependents should be removed")
+		return
+	}
+
+	if _, err = factory.GetObject("testObjName"); !ErrObjectDefintionNotExist.IsEqual(err) {
+		t.Errorf("removed definition should not exist, got: %v", err)
+		return
+	}
+}
+
+func TestClassicFactoryRedefineAndRefresh(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	newObject := func(value string) NewObjectFunc {
+		return func(Options) (interface{}, error) {
+			return &testDestroyObject{BValue: value}, nil
+		}
+	}
+
+	if err = factory.Define("feature", Singleton, "testDestroyObject",
+		DefOptOfNewObjectFunc(newObject("v1")),
+		DefOptOfDestroyFunc("Close")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("consumer", Singleton, "testFeatureConsumer", DefOptOfObjectRef("Feature", "feature")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("consumer"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	v1 := obj.(*testFeatureConsumer).Feature
+
+	if err = factory.Redefine("feature", Singleton, "testDestroyObject",
+		DefOptOfNewObjectFunc(newObject("v2")),
+		DefOptOfDestroyFunc("Close")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !v1.destroyed {
+		t.Error("the instance of old definition should be destroyed")
+		return
+	}
+
+	if obj, err = factory.GetObject("feature"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	v2 := obj.(*testDestroyObject)
+	if v2.BValue != "v2" {
+		t.Errorf("the singleton should be rebuilt by new definition, got: %s", v2.BValue)
+		return
+	}
+
+	if obj, err = factory.GetObject("consumer"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj.(*testFeatureConsumer).Feature != v2 {
+		t.Error("the dependents should be rebuilt with the new definition")
+		return
+	}
+
+	if err = factory.Refresh("feature"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj, err = factory.GetObject("feature"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !v2.destroyed || obj == v2 {
+		t.Error("refresh should rebuild the singleton")
+		return
+	}
+
+	if err = factory.Redefine("notExist", Singleton, "testDestroyObject"); !ErrObjectDefintionNotExist.IsEqual(err) {
+		t.Errorf("redefine not exist definition should fail, got: %v", err)
+		return
+	}
+}
+
+func TestClassicFactoryEvictSingleton(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("keyed", Singleton, "testDestroyObject", DefOptOfKeyedSingleton(), DefOptOfDestroyFunc("Close")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var destroyed []string
+	factory.Subscribe(func(event Event) {
+		destroyed = append(destroyed, event.Instance.Key())
+	}, SubscribeOptOfTypes(InstanceDestroyed))
+
+	objs := make([]*testDestroyObject, 2)
+	for i, id := range []string{"1", "2"} {
+		obj, err := factory.GetObject("keyed", Options{"id": id})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		objs[i] = obj.(*testDestroyObject)
+	}
+
+	keys, err := factory.SingletonKeys("keyed")
+	if err != nil || len(keys) != 2 {
+		t.Errorf("keyed singleton should have 2 keys, got: %v, %v", keys, err)
+		return
+	}
+
+	objIns, _ := factory.getInstance(keyedInstanceKey("keyed", keys[0]))
+	evicted := objIns.Instance().(*testDestroyObject)
+
+	if err = factory.EvictSingleton("keyed", keys[0]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Publisher().Publish(testOrderPlaced{ID: "1"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !evicted.destroyed || evicted.events != 0 || len(destroyed) != 1 || destroyed[0] != keys[0] {
+		t.Errorf("the evicted singleton should be destroyed and unsubscribed, destroyed: %v, events: %d", evicted.destroyed, evicted.events)
+		return
+	}
+
+	for _, obj := range objs {
+		if obj != evicted && (obj.destroyed || obj.events != 1) {
+			t.Error("the other keyed singleton should be kept")
+			return
+		}
+	}
+}
+
+func TestClassicFactoryRebuildKeepsOldInstances(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("other", Singleton, "testObjectB"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("feature", Singleton, "testDestroyObject", DefOptOfDestroyFunc("Close")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj, err := factory.GetObject("feature")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	v1 := obj.(*testDestroyObject)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- factory.Redefine("feature", Singleton, "testDestroyObject",
+			DefOptOfNewObjectFunc(func(Options) (interface{}, error) {
+				// the constructor could get objects in rebuild
+				if _, err := factory.GetObject("other"); err != nil {
+					return nil, err
+				}
+
+				close(started)
+				<-release
+
+				return nil, errors.New("bad feature")
+			}),
+			DefOptOfDestroyFunc("Close"))
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Error("the constructor getting objects in rebuild should not deadlock")
+		return
+	}
+
+	if obj, err = factory.GetObject("feature"); err != nil || obj != v1 {
+		t.Errorf("the old instance should be kept in rebuild, got: %v", err)
+		return
+	}
+
+	close(release)
+
+	if err = <-done; err == nil {
+		t.Error("the failed rebuild should fail")
+		return
+	}
+
+	if obj, err = factory.GetObject("feature"); err != nil || obj != v1 || v1.destroyed {
+		t.Errorf("the old instance should be kept after rollback, got: %v", err)
+		return
+	}
+}
@@ -0,0 +1,80 @@
+package factory
+
+import (
+	"context"
+)
+
+// DefinitionPostProcessor is called once with all registered definitions
+// before the first GetObject or Start, and with every definition registered
+// after that, it could rewrite definitions by ObjectDefinition.Apply
+type DefinitionPostProcessor interface {
+	PostProcessDefinitions(defs []*ObjectDefinition) error
+}
+
+func (p *ClassicFactory) RegisterDefinitionPostProcessor(processor DefinitionPostProcessor) (err error) {
+	p.prepareLocker.Lock()
+	defer p.prepareLocker.Unlock()
+
+	if p.prepared {
+		if err = processor.PostProcessDefinitions(p.getObjDefinitions()); err != nil {
+			return
+		}
+	}
+
+	p.defPostProcessors = append(p.defPostProcessors, processor)
+
+	return
+}
+
+func (p *ClassicFactory) prepare() (err error) {
+	p.prepareLocker.Lock()
+	defer p.prepareLocker.Unlock()
+
+	if p.prepared {
+		return p.prepareErr
+	}
+
+	p.prepared = true
+	p.prepareErr = p.postProcessDefinitions(p.getObjDefinitions())
+
+	return p.prepareErr
+}
+
+func (p *ClassicFactory) postProcessDefinitions(defs []*ObjectDefinition) (err error) {
+	for _, processor := range p.defPostProcessors {
+		if err = processor.PostProcessDefinitions(defs); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Start run the definition post processors and create all active singletons
+// which are not lazy init or keyed
+func (p *ClassicFactory) Start() (err error) {
+	return p.StartCtx(context.Background())
+}
+
+// StartCtx is the same as Start, the creation of singletons stops when the ctx done
+func (p *ClassicFactory) StartCtx(ctx context.Context) (err error) {
+
+	if err = p.prepare(); err != nil {
+		return
+	}
+
+	for _, def := range p.getObjDefinitions() {
+		if def.Scope() != Singleton || def.IsLazyInit() || def.IsKeyedSingleton() || !p.isActive(def) {
+			continue
+		}
+
+		if _, err = p.getObject(withResolutionStep(ctx, "", def.Name()), def, nil); err != nil {
+			p.publish(Event{Type: ResolutionFailed, Definition: def, Err: err})
+			return
+		}
+	}
+
+	p.publish(Event{Type: FactoryStarted})
+
+	return
+}
@@ -0,0 +1,181 @@
+package factory
+
+import (
+	"fmt"
+	"github.com/gogap/errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxPlaceholderDepth = 16
+
+// Environment is the layered properties of the factory, the property of
+// the former source overrides the latter ones
+type Environment struct {
+	locker  sync.RWMutex
+	sources []PropertySource
+}
+
+func NewEnvironment(sources ...PropertySource) *Environment {
+	return &Environment{
+		sources: sources,
+	}
+}
+
+func (p *Environment) AddFirst(source PropertySource) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	p.sources = append([]PropertySource{source}, p.sources...)
+}
+
+func (p *Environment) AddLast(source PropertySource) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	p.sources = append(p.sources, source)
+}
+
+func (p *Environment) Sources() []PropertySource {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	return append([]PropertySource(nil), p.sources...)
+}
+
+func (p *Environment) Property(key string) (value interface{}, exist bool) {
+	for _, source := range p.Sources() {
+		if value, exist = source.Property(key); exist {
+			return
+		}
+	}
+
+	return
+}
+
+// Keys returns the sorted keys of all sources
+func (p *Environment) Keys() (keys []string) {
+	encountered := map[string]bool{}
+
+	for _, source := range p.Sources() {
+		for _, key := range source.Keys() {
+			if !encountered[key] {
+				encountered[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	return
+}
+
+// Resolve replace the ${key:default} placeholders in s, if s is exactly one
+// placeholder, the property value is returned without converting to string
+func (p *Environment) Resolve(s string) (v interface{}, err error) {
+	return p.resolve(s, 0)
+}
+
+func (p *Environment) resolve(s string, depth int) (v interface{}, err error) {
+
+	if depth > maxPlaceholderDepth {
+		err = ErrPlaceholderTooDeep.New(errors.Params{"value": s})
+		return
+	}
+
+	start := strings.Index(s, "${")
+	if start < 0 {
+		v = s
+		return
+	}
+
+	end := strings.Index(s[start:], "}")
+	if end < 0 {
+		v = s
+		return
+	}
+	end += start
+
+	key, defaultValue, hasDefault := strings.Cut(s[start+2:end], ":")
+	key = strings.TrimSpace(key)
+
+	var value interface{}
+	var exist bool
+	if value, exist = p.Property(key); !exist {
+		if !hasDefault {
+			err = ErrUnresolvedPlaceholder.New(errors.Params{"key": key})
+			return
+		}
+		value = defaultValue
+	}
+
+	if strValue, ok := value.(string); ok {
+		if value, err = p.resolve(strValue, depth+1); err != nil {
+			return
+		}
+	}
+
+	if start == 0 && end == len(s)-1 {
+		v = value
+		return
+	}
+
+	var rest interface{}
+	if rest, err = p.resolve(s[end+1:], depth); err != nil {
+		return
+	}
+
+	v = s[:start] + fmt.Sprint(value) + fmt.Sprint(rest)
+
+	return
+}
+
+// ResolveOptions returns a copy of opts with all the placeholders of string values resolved
+func (p *Environment) ResolveOptions(opts Options) (resolved Options, err error) {
+	if opts == nil {
+		return
+	}
+
+	var v interface{}
+	if v, err = p.resolveValue(map[string]interface{}(opts)); err != nil {
+		return
+	}
+
+	resolved = Options(v.(map[string]interface{}))
+
+	return
+}
+
+func (p *Environment) resolveValue(value interface{}) (v interface{}, err error) {
+	switch val := value.(type) {
+	case string:
+		return p.Resolve(val)
+	case Options:
+		if v, err = p.resolveValue(map[string]interface{}(val)); err != nil {
+			return
+		}
+		v = Options(v.(map[string]interface{}))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if m[k], err = p.resolveValue(item); err != nil {
+				return
+			}
+		}
+		v = m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, item := range val {
+			if l[i], err = p.resolveValue(item); err != nil {
+				return
+			}
+		}
+		v = l
+	default:
+		v = value
+	}
+
+	return
+}
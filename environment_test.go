@@ -0,0 +1,114 @@
+package factory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironmentResolve(t *testing.T) {
+
+	var err error
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.properties")
+
+	if err = os.WriteFile(filename, []byte("# comment\nhub.id = HUB-FILE\nhub.name=${hub.id}-name\n"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var fileSource *FilePropertySource
+	if fileSource, err = NewFilePropertySource(filename); err != nil {
+		t.Error(err)
+		return
+	}
+
+	env := NewEnvironment(
+		NewMapPropertySource("overrides", map[string]interface{}{"hub": map[string]interface{}{"id": "HUB-MAP"}, "hub.size": 4}),
+		fileSource,
+	)
+
+	var v interface{}
+	if v, err = env.Resolve("${hub.id}"); err != nil || v != "HUB-MAP" {
+		t.Errorf("former source should override latter, got: %v, %v", v, err)
+		return
+	}
+
+	if v, err = env.Resolve("${hub.size}"); err != nil || v != 4 {
+		t.Errorf("single placeholder should keep the property type, got: %v, %v", v, err)
+		return
+	}
+
+	if v, err = env.Resolve("name: ${hub.name}, owner: ${owner:GoGap}"); err != nil || v != "name: HUB-MAP-name, owner: GoGap" {
+		t.Errorf("bad resolved value: %v, %v", v, err)
+		return
+	}
+
+	if _, err = env.Resolve("${not.exist}"); !ErrUnresolvedPlaceholder.IsEqual(err) {
+		t.Errorf("unresolved placeholder should return ErrUnresolvedPlaceholder, got: %v", err)
+		return
+	}
+
+	var opts Options
+	if opts, err = env.ResolveOptions(Options{"id": "${hub.id}", "nested": map[string]interface{}{"size": "${hub.size}"}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if opts["id"] != "HUB-MAP" || opts["nested"].(map[string]interface{})["size"] != 4 {
+		t.Errorf("bad resolved options: %v", opts)
+		return
+	}
+}
+
+func TestClassicFactoryOfPlaceholder(t *testing.T) {
+
+	var err error
+
+	env := NewEnvironment(NewMapPropertySource("test", map[string]interface{}{"b.value": "VB-ENV"}))
+	factory := NewClassicFactory(nil, FactoryOptOfEnvironment(env))
+
+	newB := func(opts Options) (v interface{}, err error) {
+		b := &testObjectB{}
+		opts.Get("value", &b.BValue)
+		return b, nil
+	}
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject",
+		DefOptOfObjectRef("ObjB", "testObjBName", Options{"value": "${b.value}"})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("testObjName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj.(*testObject).ObjB.BValue != "VB-ENV" {
+		t.Error("placeholder of ref options not resolved")
+		return
+	}
+
+	if obj, err = factory.GetObject("testObjBName", Options{"value": "${b.other:VB-DEFAULT}"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj.(*testObjectB).BValue != "VB-DEFAULT" {
+		t.Error("placeholder of call options not resolved")
+		return
+	}
+
+	if _, err = factory.GetObject("testObjBName", Options{"value": "${b.other}"}); !ErrUnresolvedPlaceholder.IsEqual(err) {
+		t.Errorf("unresolved placeholder should fail, got: %v", err)
+		return
+	}
+}
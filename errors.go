@@ -32,4 +32,53 @@ var (
 	ErrFieldIsZeroValue                  = errors.TN(ErrNamespace, 1021, "filed is zero value, field name: {{.name}}")
 	ErrBadRefOrderLength                 = errors.TN(ErrNamespace, 1022, "ref order does not equal definition refs")
 	ErrRefOrderContainNonExistRef        = errors.TN(ErrNamespace, 1023, "ref order contain non exist def ref, name: {{.name}}")
+	ErrRefTypeNotMatch                   = errors.TN(ErrNamespace, 1024, "ref object type not match, name: {{.name}}, expected type: {{.type}}")
+	ErrCollectionFieldTypeNotSupported   = errors.TN(ErrNamespace, 1025, "collection field should be []T or map[string]T, T should be ptr or interface, field name: {{.name}}, type: {{.type}}")
+	ErrNoCandidateDefinition             = errors.TN(ErrNamespace, 1026, "no definition match the type, type: {{.type}}, qualifier: {{.qualifier}}")
+	ErrAmbiguousDefinitions              = errors.TN(ErrNamespace, 1027, "several definitions match the type and none is primary, type: {{.type}}, qualifier: {{.qualifier}}, candidates: {{.candidates}}")
+	ErrObjectDefinitionNotActive         = errors.TN(ErrNamespace, 1028, "object definition not active, name: {{.name}}, profiles: {{.profiles}}")
+	ErrInitialFuncNotExist               = errors.TN(ErrNamespace, 1029, "initial func not exist, name: {{.name}}, func: {{.func}}")
+	ErrBadInitialFunc                    = errors.TN(ErrNamespace, 1030, "initial func should be func() or func() error, name: {{.name}}, func: {{.func}}")
+	ErrFieldIsNotRef                     = errors.TN(ErrNamespace, 1031, "field is not a ref, field name: {{.name}}")
+	ErrUnresolvedPlaceholder             = errors.TN(ErrNamespace, 1032, "unresolved placeholder, key: {{.key}}")
+	ErrPlaceholderTooDeep                = errors.TN(ErrNamespace, 1033, "placeholder nested too deep, value: {{.value}}")
+	ErrBadPropertyFile                   = errors.TN(ErrNamespace, 1034, "bad property file, file: {{.file}}, error: {{.err}}")
+	ErrCouldNotConvertValue              = errors.TN(ErrNamespace, 1035, "could not convert value {{.value}} to type {{.type}}")
+	ErrOptionsTypeMustBeStruct           = errors.TN(ErrNamespace, 1036, "options type must be struct, name: {{.name}}")
+	ErrInvalidOption                     = errors.TN(ErrNamespace, 1037, "invalid option, name: {{.name}}, key: {{.key}}, reason: {{.reason}}")
+	ErrOptionTargetShouldBePtr           = errors.TN(ErrNamespace, 1038, "the value to get option should be a non-nil ptr, option name: {{.name}}")
+	ErrBadOptionValue                    = errors.TN(ErrNamespace, 1039, "bad option value, option name: {{.name}}, error: {{.err}}")
+	ErrCouldNotHashOptions               = errors.TN(ErrNamespace, 1040, "could not hash options of keyed singleton, error: {{.err}}")
+	ErrPooledObjectShouldBorrow          = errors.TN(ErrNamespace, 1041, "object of pooled scope should be borrowed from factory, name: {{.name}}")
+	ErrObjectIsNotPooled                 = errors.TN(ErrNamespace, 1042, "object is not pooled scope, name: {{.name}}")
+	ErrBadPoolConfig                     = errors.TN(ErrNamespace, 1043, "bad pool config, name: {{.name}}, min size: {{.min}}, max size: {{.max}}")
+	ErrPoolExhausted                     = errors.TN(ErrNamespace, 1044, "pool exhausted, name: {{.name}}, error: {{.err}}")
+	ErrPooledObjectNotComparable         = errors.TN(ErrNamespace, 1045, "pooled object should be comparable, such as ptr, name: {{.name}}")
+	ErrObjectNotBorrowed                 = errors.TN(ErrNamespace, 1046, "object is not borrowed from factory")
+	ErrObjectCreationTimeout             = errors.TN(ErrNamespace, 1047, "object creation timeout, name: {{.name}}")
+	ErrObjectCreationCanceled            = errors.TN(ErrNamespace, 1048, "object creation canceled, name: {{.name}}")
+	ErrCouldNotResolveObject             = errors.TN(ErrNamespace, 1049, "could not resolve object, name: {{.name}}, error: {{.err}}")
+	ErrDefinitionStillReferenced         = errors.TN(ErrNamespace, 1050, "object definition is still referenced, name: {{.name}}, referenced by: {{.refs}}")
+	ErrDestroyFuncNotExist               = errors.TN(ErrNamespace, 1051, "destroy func not exist, name: {{.name}}, func: {{.func}}")
+	ErrBadDestroyFunc                    = errors.TN(ErrNamespace, 1052, "destroy func should be func() or func() error, name: {{.name}}, func: {{.func}}")
+	ErrBadDefinitionFile                 = errors.TN(ErrNamespace, 1053, "bad definition file, file: {{.file}}, error: {{.err}}")
+	ErrUnknownScope                      = errors.TN(ErrNamespace, 1054, "unknown scope: {{.scope}}")
+	ErrEventListenerFailed               = errors.TN(ErrNamespace, 1055, "event listener failed, name: {{.name}}, event: {{.event}}, error: {{.err}}")
+	ErrBadEventListener                  = errors.TN(ErrNamespace, 1056, "event listener should be func(E) or func(E) error, name: {{.name}}, func: {{.func}}")
+	ErrObjectIsNotSingleton              = errors.TN(ErrNamespace, 1057, "object is not singleton scope, name: {{.name}}")
+	ErrHealthCheckFailed                 = errors.TN(ErrNamespace, 1058, "health check failed, name: {{.name}}, error: {{.err}}")
+	ErrHealthCheckTimeout                = errors.TN(ErrNamespace, 1059, "health check timeout, name: {{.name}}")
+	ErrBadProxyInterface                 = errors.TN(ErrNamespace, 1060, "proxy interface should be the nil pointer of interface, got: {{.interface}}")
+	ErrProxyNotRegistered                = errors.TN(ErrNamespace, 1061, "proxy of interface not registered, name: {{.name}}, interface: {{.interface}}")
+	ErrProxyInterfaceNotImplemented      = errors.TN(ErrNamespace, 1062, "object not implement the proxy interface, name: {{.name}}, interface: {{.interface}}")
+	ErrInterceptorWithoutProxy           = errors.TN(ErrNamespace, 1063, "interceptors require the proxy interface, name: {{.name}}")
+	ErrDecoratorFieldNotExist            = errors.TN(ErrNamespace, 1064, "decorator field not exist or not exported, name: {{.name}}, field: {{.field}}")
+	ErrFactoryNotSupported               = errors.TN(ErrNamespace, 1065, "factory not implement {{.interface}}")
+	ErrBadObjectDefinitionName           = errors.TN(ErrNamespace, 1066, "object definition name should not contain {{.separator}}, name: {{.name}}")
 )
+
+func isMissingDefinitionError(err error) bool {
+	return ErrObjectDefintionNotExist.IsEqual(err) ||
+		ErrObjectDefinitionNotActive.IsEqual(err) ||
+		ErrNoCandidateDefinition.IsEqual(err)
+}
@@ -0,0 +1,256 @@
+package factory
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType int
+
+const (
+	DefinitionRegistered EventType = iota
+	InstanceCreated
+	InstanceInitialized
+	InstanceDestroyed
+	FactoryStarted
+	FactoryClosed
+	ResolutionFailed
+)
+
+func (p EventType) String() string {
+	switch p {
+	case DefinitionRegistered:
+		return "DefinitionRegistered"
+	case InstanceCreated:
+		return "InstanceCreated"
+	case InstanceInitialized:
+		return "InstanceInitialized"
+	case InstanceDestroyed:
+		return "InstanceDestroyed"
+	case FactoryStarted:
+		return "FactoryStarted"
+	case FactoryClosed:
+		return "FactoryClosed"
+	case ResolutionFailed:
+		return "ResolutionFailed"
+	}
+
+	return "Unknown"
+}
+
+// Event is the lifecycle notification of factory, Definition, Instance and Err
+// are set according to the type
+type Event struct {
+	Type       EventType
+	Time       time.Time
+	Definition *ObjectDefinition
+	Instance   *ObjectInstance
+	Err        error
+}
+
+type EventListener func(event Event)
+
+type SubscribeOption struct {
+	f func(p *subscription)
+}
+
+// SubscribeOptOfAsync make the listener called in its own goroutine, the events
+// are queued, so the listener never blocks the factory
+func SubscribeOptOfAsync() SubscribeOption {
+	return SubscribeOption{func(p *subscription) {
+		p.async = true
+	}}
+}
+
+// SubscribeOptOfTypes make the listener only receive the events of types
+func SubscribeOptOfTypes(types ...EventType) SubscribeOption {
+	return SubscribeOption{func(p *subscription) {
+		if p.types == nil {
+			p.types = make(map[EventType]bool)
+		}
+
+		for _, typ := range types {
+			p.types[typ] = true
+		}
+	}}
+}
+
+type subscription struct {
+	listener EventListener
+	types    map[EventType]bool
+	async    bool
+
+	locker  sync.Mutex
+	queue   []Event
+	closed  bool
+	signal  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func (p *subscription) accept(event Event) bool {
+	return p.types == nil || p.types[event.Type]
+}
+
+func (p *subscription) deliver(event Event) {
+	if !p.async {
+		p.listener(event)
+		return
+	}
+
+	p.locker.Lock()
+	if !p.closed {
+		p.queue = append(p.queue, event)
+	}
+	p.locker.Unlock()
+
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (p *subscription) run() {
+	defer close(p.stopped)
+
+	for {
+		select {
+		case <-p.signal:
+		case <-p.done:
+		}
+
+		for {
+			p.locker.Lock()
+			queue := p.queue
+			p.queue = nil
+			p.locker.Unlock()
+
+			if len(queue) == 0 {
+				break
+			}
+
+			for _, event := range queue {
+				p.listener(event)
+			}
+		}
+
+		p.locker.Lock()
+		done := p.closed && len(p.queue) == 0
+		p.locker.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// close stop the goroutine of async listener after the queued events delivered,
+// it does not wait, so the listener could unsubscribe itself
+func (p *subscription) close() {
+	if !p.async {
+		return
+	}
+
+	p.locker.Lock()
+	alreadyClosed := p.closed
+	p.closed = true
+	p.locker.Unlock()
+
+	if !alreadyClosed {
+		close(p.done)
+	}
+}
+
+// wait until the goroutine of async listener stopped
+func (p *subscription) wait() {
+	if p.async {
+		<-p.stopped
+	}
+}
+
+// Subscribe add the listener of factory events, the listener is called synchronously
+// unless SubscribeOptOfAsync, the returned func removes the listener, the events
+// already queued for the async listener are still delivered after it returns
+func (p *ClassicFactory) Subscribe(listener EventListener, opts ...SubscribeOption) (unsubscribe func()) {
+
+	sub := &subscription{listener: listener}
+
+	for _, opt := range opts {
+		opt.f(sub)
+	}
+
+	if sub.async {
+		sub.signal = make(chan struct{}, 1)
+		sub.done = make(chan struct{})
+		sub.stopped = make(chan struct{})
+		go sub.run()
+	}
+
+	p.eventLocker.Lock()
+	p.subscriptions = append(p.subscriptions, sub)
+	p.eventLocker.Unlock()
+
+	return func() {
+		p.eventLocker.Lock()
+		for i, s := range p.subscriptions {
+			if s == sub {
+				p.subscriptions = append(p.subscriptions[:i:i], p.subscriptions[i+1:]...)
+				break
+			}
+		}
+		p.eventLocker.Unlock()
+
+		sub.close()
+	}
+}
+
+func (p *ClassicFactory) publish(event Event) {
+
+	p.eventLocker.RLock()
+	subscriptions := p.subscriptions
+	p.eventLocker.RUnlock()
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	event.Time = time.Now()
+
+	for _, sub := range subscriptions {
+		if sub.accept(event) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// Close destroy all the cached instances and the idle pooled objects, and stop
+// the async listeners after the FactoryClosed event delivered
+func (p *ClassicFactory) Close() (err error) {
+
+	// destroy the dependents before their dependencies
+	detached := &detachedInstances{}
+	for _, def := range p.destroyOrder() {
+		defDetached := p.detachInstances(def.Name())
+		detached.instances = append(detached.instances, defDetached.instances...)
+		detached.pools = append(detached.pools, defDetached.pools...)
+	}
+
+	err = p.destroy(detached)
+
+	p.publish(Event{Type: FactoryClosed, Err: err})
+
+	p.eventLocker.Lock()
+	subscriptions := p.subscriptions
+	p.subscriptions = nil
+	p.eventLocker.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.close()
+	}
+
+	for _, sub := range subscriptions {
+		sub.wait()
+	}
+
+	return
+}
@@ -0,0 +1,240 @@
+package factory
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClassicFactoryEvents(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var events []Event
+	factory.Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+
+	var asyncLocker sync.Mutex
+	var asyncTypes []EventType
+	factory.Subscribe(func(event Event) {
+		asyncLocker.Lock()
+		asyncTypes = append(asyncTypes, event.Type)
+		asyncLocker.Unlock()
+	}, SubscribeOptOfAsync(), SubscribeOptOfTypes(InstanceDestroyed, FactoryClosed))
+
+	if err = factory.Define("testObjBName", Singleton, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("notExist"); err == nil {
+		t.Error("get not exist object should fail")
+		return
+	}
+
+	if err = factory.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []EventType{DefinitionRegistered, InstanceCreated, InstanceInitialized, FactoryStarted, ResolutionFailed, InstanceDestroyed, FactoryClosed}
+
+	if len(events) != len(expected) {
+		t.Errorf("bad events: %v", events)
+		return
+	}
+
+	for i, typ := range expected {
+		if events[i].Type != typ {
+			t.Errorf("event %d should be %s, got: %s", i, typ, events[i].Type)
+			return
+		}
+	}
+
+	if events[1].Instance.Id() == "" || events[1].Instance != events[2].Instance || events[2].Instance != events[5].Instance {
+		t.Error("the instance events should carry the same instance")
+		return
+	}
+
+	if !ErrObjectDefintionNotExist.IsEqual(events[4].Err) {
+		t.Errorf("ResolutionFailed should carry the error, got: %v", events[4].Err)
+		return
+	}
+
+	asyncLocker.Lock()
+	defer asyncLocker.Unlock()
+
+	if len(asyncTypes) != 2 || asyncTypes[0] != InstanceDestroyed || asyncTypes[1] != FactoryClosed {
+		t.Errorf("async listener should receive the filtered events before Close returns, got: %v", asyncTypes)
+		return
+	}
+}
+
+func TestClassicFactoryEventsAsyncUnsubscribe(t *testing.T) {
+
+	factory := NewClassicFactory(nil)
+
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	delivered := make(chan struct{})
+
+	var received []EventType
+	unsubscribe := factory.Subscribe(func(event Event) {
+		if event.Type == FactoryStarted {
+			close(busy)
+			<-release
+		}
+		received = append(received, event.Type)
+		if event.Type == FactoryClosed {
+			close(delivered)
+		}
+	}, SubscribeOptOfAsync())
+
+	factory.publish(Event{Type: FactoryStarted})
+	<-busy
+
+	// the second event is queued with the signal buffered while the listener is busy
+	factory.publish(Event{Type: FactoryClosed})
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsubscribe()
+		close(unsubscribed)
+	}()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Error("unsubscribe of busy async listener should not block")
+		return
+	}
+
+	close(release)
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Error("the queued events should be delivered after unsubscribed")
+		return
+	}
+
+	if len(received) != 2 || received[1] != FactoryClosed {
+		t.Errorf("the queued events should be delivered in order, got: %v", received)
+		return
+	}
+}
+
+func TestClassicFactoryEventsAsyncUnsubscribeSelf(t *testing.T) {
+
+	factory := NewClassicFactory(nil)
+
+	unsubscribed := make(chan struct{})
+
+	var unsubscribe func()
+	unsubscribe = factory.Subscribe(func(event Event) {
+		// the listener unsubscribe itself
+		unsubscribe()
+		close(unsubscribed)
+	}, SubscribeOptOfAsync(), SubscribeOptOfTypes(FactoryStarted))
+
+	factory.publish(Event{Type: FactoryStarted})
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Error("the async listener unsubscribing itself should not block")
+		return
+	}
+
+	if err := factory.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestClassicFactoryEventsCloseOrder(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("base", Singleton, "testDestroyObject", DefOptOfDestroyFunc("Close")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("consumer", Singleton, "testFeatureConsumer", DefOptOfObjectRef("Feature", "base")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var destroyed []string
+	factory.Subscribe(func(event Event) {
+		destroyed = append(destroyed, event.Definition.Name())
+	}, SubscribeOptOfTypes(InstanceDestroyed))
+
+	if err = factory.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(destroyed) != 2 || destroyed[0] != "consumer" || destroyed[1] != "base" {
+		t.Errorf("the dependents should be destroyed before their dependencies, got: %v", destroyed)
+		return
+	}
+}
+
+func TestClassicFactoryEventsListenerGetObject(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var objs []interface{}
+	factory.Subscribe(func(event Event) {
+		// the listener could get the registered object
+		if obj, err := factory.GetObject(event.Definition.Name()); err == nil {
+			objs = append(objs, obj)
+		}
+	}, SubscribeOptOfTypes(DefinitionRegistered))
+
+	done := make(chan error, 1)
+	go func() {
+		if err := factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+			done <- err
+			return
+		}
+
+		done <- factory.Redefine("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB))
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Error("the listener of DefinitionRegistered getting objects should not deadlock")
+		return
+	}
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(objs) != 2 {
+		t.Errorf("the listener should get the objects, got: %d", len(objs))
+		return
+	}
+}
@@ -18,7 +18,8 @@ func NewHub(opts factory.Options) (hub interface{}, err error) {
 }
 
 type Wheel struct {
-	ID string
+	ID    string
+	owner string
 
 	Hub *Hub
 }
@@ -26,12 +27,13 @@ type Wheel struct {
 func NewWheel(opts factory.Options) (wheel interface{}, err error) {
 	w := &Wheel{}
 	opts.Get("id", &w.ID)
+	opts.Get("owner", &w.owner)
 	wheel = w
 	return
 }
 
 func (p *Wheel) Run() {
-	fmt.Printf("Wheel Running, ID: %s, HubID: %s\n", p.ID, p.Hub.ID)
+	fmt.Printf("%s' Wheel Running, ID: %s, HubID: %s\n", p.owner, p.ID, p.Hub.ID)
 }
 
 type Car struct {
@@ -77,6 +79,7 @@ func main() {
 
 	err = carFactory.Define("mycar", factory.Prototype, "Skoda",
 		factory.DefOptOfNewObjectFunc(NewCar),
+		factory.DefOptOfPropagateOptions("owner"),
 		factory.DefOptOfObjectRef("Wheel1", "wheel", factory.Options{"id": "1"}),
 		factory.DefOptOfObjectRef("Wheel2", "wheel", factory.Options{"id": "2"}),
 		factory.DefOptOfObjectRef("Wheel3", "wheel", factory.Options{"id": "3"}),
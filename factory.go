@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"context"
 	"reflect"
 )
 
@@ -16,3 +17,51 @@ type Factory interface {
 
 	Define(name string, scope Scope, model string, opts ...DefinitionOption) error
 }
+
+// Introspector is the optional interface of Factory exposing its definitions and instances
+type Introspector interface {
+	ActiveProfiles() []string
+	Environment() *Environment
+
+	GetRefs(name string) (refs []RefInfo, err error)
+	Definitions() []*ObjectDefinition
+	Instances() []*ObjectInstance
+}
+
+// Lifecycle is the optional interface of Factory managing the instances
+type Lifecycle interface {
+	Start() error
+	StartCtx(ctx context.Context) error
+
+	Refresh(name string) error
+	DestroySingleton(name string) error
+
+	Health(ctx context.Context) HealthReport
+	Close() error
+}
+
+// DefinitionRegistry is the optional interface of Factory changing the definitions at runtime
+type DefinitionRegistry interface {
+	Redefine(name string, scope Scope, model string, opts ...DefinitionOption) error
+	RemoveDefinition(name string, cascade bool) error
+	ApplyDefinitions(changes ...DefinitionChange) error
+}
+
+var (
+	_ Factory            = (*ClassicFactory)(nil)
+	_ Introspector       = (*ClassicFactory)(nil)
+	_ Lifecycle          = (*ClassicFactory)(nil)
+	_ DefinitionRegistry = (*ClassicFactory)(nil)
+)
+
+type FactoryOption struct {
+	f func(p *ClassicFactory)
+}
+
+func FactoryOptOfEnvironment(env *Environment) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		if env != nil {
+			p.environment = env
+		}
+	}}
+}
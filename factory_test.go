@@ -1,8 +1,15 @@
 package factory
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type testObjectB struct {
@@ -20,10 +27,29 @@ type testObject struct {
 	ObjC testObjectC
 }
 
+type testCyclicA struct {
+	B *testCyclicB
+}
+
+type testCyclicB struct {
+	A *testCyclicA
+}
+
 func init() {
 	RegisterModel((*testObjectB)(nil), "testObjectB")
 	RegisterModel((*testObjectC)(nil), "testObjectC")
 	RegisterModel((*testObject)(nil), "testObject")
+	RegisterModel((*testProviderObject)(nil), "testProviderObject")
+	RegisterModel((*testHandlerA)(nil), "testHandlerA")
+	RegisterModel((*testHandlerB)(nil), "testHandlerB")
+	RegisterModel((*testRouter)(nil), "testRouter")
+	RegisterModel((*testTypedRefObject)(nil), "testTypedRefObject")
+	RegisterModel((*testOptionalRefObject)(nil), "testOptionalRefObject")
+	RegisterModel((*testInitObject)(nil), "testInitObject")
+	RegisterModel((*testCyclicA)(nil), "testCyclicA")
+	RegisterModel((*testCyclicB)(nil), "testCyclicB")
+	RegisterModel((*testLateObject)(nil), "testLateObject")
+	RegisterModel((*testSliceObject)(nil), "testSliceObject")
 }
 
 func newTestObjectB(opts Options) (v interface{}, err error) {
@@ -119,6 +145,49 @@ func TestClassicFactorySingleton(t *testing.T) {
 	}
 }
 
+func TestClassicFactoryOfDefinitionQueries(t *testing.T) {
+
+	var err error
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("singletonName", Singleton, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("prototypeName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !factory.IsSingleton("singletonName") || factory.IsPrototype("singletonName") {
+		t.Error("the singleton definition should be singleton")
+		return
+	}
+
+	if !factory.IsPrototype("prototypeName") || factory.IsSingleton("prototypeName") {
+		t.Error("the prototype definition should be prototype")
+		return
+	}
+
+	if !factory.IsTypeMatch("singletonName", reflect.TypeOf(&testObjectB{})) || factory.IsTypeMatch("singletonName", reflect.TypeOf(&testObject{})) {
+		t.Error("the type of definition should be matched")
+		return
+	}
+
+	// the unknown names should not panic
+	if factory.IsSingleton("notExist") || factory.IsPrototype("notExist") || factory.IsTypeMatch("notExist", reflect.TypeOf(&testObjectB{})) {
+		t.Error("the not exist definition should not match")
+		return
+	}
+
+	// the empty options should not panic
+	if _, err = factory.GetObject("prototypeName", []Options{}...); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestClassicFactoryOfObjRef(t *testing.T) {
 
 	var err error
@@ -164,3 +233,1124 @@ func TestClassicFactoryOfObjRef(t *testing.T) {
 	}
 
 }
+
+type testProviderObject struct {
+	ProvB Provider[*testObjectB]
+	FuncB func() (*testObjectB, error)
+}
+
+func TestClassicFactoryOfProviderRef(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	created := 0
+	newB := func(opts Options) (v interface{}, err error) {
+		created++
+		return &testObjectB{BValue: "VB"}, nil
+	}
+
+	if err = factory.Define("testProviderObjName", Singleton, "testProviderObject",
+		DefOptOfObjectRef("ProvB", "testObjBName"),
+		DefOptOfObjectRef("FuncB", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("testProviderObjName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	objIns := obj.(*testProviderObject)
+
+	if _, err = objIns.ProvB(); err == nil {
+		t.Error("provider should fail before ref definition exist")
+		return
+	}
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if created != 0 {
+		t.Error("provider ref should not be resolved before called")
+		return
+	}
+
+	var b1, b2 *testObjectB
+	if b1, err = objIns.ProvB(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if b2, err = objIns.FuncB(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if b1 == b2 || created != 2 {
+		t.Error("provider of prototype should create new object on every call")
+		return
+	}
+
+	if b1.BValue != "VB" {
+		t.Error("provided object B's value is not 'VB'")
+		return
+	}
+}
+
+type testHandler interface {
+	Handle() string
+}
+
+type testHandlerA struct{ name string }
+
+func (p *testHandlerA) Handle() string { return "A" }
+
+type testHandlerB struct{}
+
+func (p *testHandlerB) Handle() string { return "B" }
+
+type testRouter struct {
+	Handlers    []testHandler
+	HandlersOfA map[string]*testHandlerA
+	Tagged      []testHandler
+}
+
+func TestClassicFactoryOfCollectionRef(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("handlerA1", Singleton, "testHandlerA", DefOptOfOrder(2)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("handlerA2", Singleton, "testHandlerA", DefOptOfOrder(1), DefOptOfTags("admin")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("handlerB", Singleton, "testHandlerB", DefOptOfOrder(3), DefOptOfTags("admin")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("router", Singleton, "testRouter",
+		DefOptOfCollectionRef("Handlers", ""),
+		DefOptOfCollectionRef("HandlersOfA", ""),
+		DefOptOfCollectionRef("Tagged", "admin")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("router"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	router := obj.(*testRouter)
+
+	var handled string
+	for _, h := range router.Handlers {
+		handled += h.Handle()
+	}
+
+	if handled != "AAB" {
+		t.Errorf("handlers not injected in order, got: %s", handled)
+		return
+	}
+
+	if len(router.HandlersOfA) != 2 || router.HandlersOfA["handlerA1"] == nil || router.HandlersOfA["handlerA2"] == nil {
+		t.Error("map of handler A not injected")
+		return
+	}
+
+	if len(router.Tagged) != 2 || router.Tagged[0] != testHandler(router.HandlersOfA["handlerA2"]) {
+		t.Error("tagged handlers not injected")
+		return
+	}
+}
+
+type testTypedRefObject struct {
+	Handler    testHandler
+	HandlerA   *testHandlerA
+	NewHandler Provider[testHandler]
+}
+
+func TestClassicFactoryOfTypedRef(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("handlerA1", Singleton, "testHandlerA", DefOptOfQualifier("readonly")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("handlerA2", Singleton, "testHandlerA"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("handlerB", Singleton, "testHandlerB"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("typedRef", Prototype, "testTypedRefObject",
+		DefOptOfTypedRef("Handler", "readonly"),
+		DefOptOfTypedRef("HandlerA", ""),
+		DefOptOfTypedRef("NewHandler", "")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("typedRef"); err == nil {
+		t.Error("typed ref without primary definition should be ambiguous")
+		return
+	}
+
+	if !strings.Contains(err.Error(), "handlerA1") || !strings.Contains(err.Error(), "handlerA2") {
+		t.Errorf("ambiguous error should list the candidates, got: %s", err)
+		return
+	}
+
+	if err = factory.Define("handlerA3", Singleton, "testHandlerA", DefOptOfPrimary()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("typedRef"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	objIns := obj.(*testTypedRefObject)
+
+	var handlerA1, handlerA3 interface{}
+	handlerA1, _ = factory.GetObject("handlerA1")
+	handlerA3, _ = factory.GetObject("handlerA3")
+
+	if objIns.Handler != handlerA1 {
+		t.Error("typed ref with qualifier should inject handlerA1")
+		return
+	}
+
+	if objIns.HandlerA != handlerA3 {
+		t.Error("typed ref should inject the primary definition")
+		return
+	}
+
+	var handler testHandler
+	if handler, err = objIns.NewHandler(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if handler != handlerA3 {
+		t.Error("typed provider should provide the primary definition")
+		return
+	}
+}
+
+type testOptionalRefObject struct {
+	ObjB     *testObjectB
+	Feature  *testObjectB
+	Required *testObjectB
+}
+
+func TestClassicFactoryOfOptionalRef(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("featureObjB", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB), DefOptOfProfiles("feature")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("optionalRef", Prototype, "testOptionalRefObject",
+		DefOptOfOptionalRef("ObjB", "notExistObjB"),
+		DefOptOfOptionalRef("Feature", "featureObjB"),
+		DefOptOfObjectRef("Required", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("optionalRef"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	objIns := obj.(*testOptionalRefObject)
+
+	if objIns.ObjB != nil || objIns.Feature != nil || objIns.Required == nil {
+		t.Error("optional refs should keep zero value when missing")
+		return
+	}
+
+	var refs []RefInfo
+	if refs, err = factory.GetRefs("optionalRef"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(refs) != 3 || refs[0].State != RefUnresolved || refs[1].State != RefUnresolved || refs[2].State != RefResolved {
+		t.Errorf("bad ref states: %v", refs)
+		return
+	}
+
+	factory.SetActiveProfiles("feature")
+
+	if obj, err = factory.GetObject("optionalRef"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj.(*testOptionalRefObject).Feature == nil {
+		t.Error("optional ref should be injected when the profile is active")
+		return
+	}
+
+	if err = factory.Define("brokenRef", Prototype, "testOptionalRefObject", DefOptOfObjectRef("ObjB", "notExistObjB")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if refs, err = factory.GetRefs("brokenRef"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if refs[0].State != RefBroken {
+		t.Error("missing required ref should be broken")
+		return
+	}
+}
+
+type testInitObject struct {
+	ObjB *testObjectB
+
+	initialized bool
+}
+
+func (p *testInitObject) Init() error {
+	if p.ObjB == nil {
+		return errors.New("ObjB should be injected before init")
+	}
+
+	p.initialized = true
+	return nil
+}
+
+type testWrappedObject struct {
+	original interface{}
+}
+
+type testPostProcessor struct {
+	calls []string
+}
+
+func (p *testPostProcessor) BeforeInit(obj interface{}, def *ObjectDefinition) (interface{}, error) {
+	p.calls = append(p.calls, "before:"+def.Name())
+	return obj, nil
+}
+
+func (p *testPostProcessor) AfterInit(obj interface{}, def *ObjectDefinition) (interface{}, error) {
+	p.calls = append(p.calls, "after:"+def.Name())
+
+	if def.Name() == "initObj" {
+		return &testWrappedObject{original: obj}, nil
+	}
+
+	return obj, nil
+}
+
+func TestClassicFactoryOfPostProcessor(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	processor := &testPostProcessor{}
+	factory.RegisterPostProcessor(processor)
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("initObj", Singleton, "testInitObject",
+		DefOptOfObjectRef("ObjB", "testObjBName"),
+		DefOptOfInitialFunc("Init")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj, obj2 interface{}
+	if obj, err = factory.GetObject("initObj"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	wrapped, ok := obj.(*testWrappedObject)
+	if !ok {
+		t.Error("object should be replaced by post processor")
+		return
+	}
+
+	if !wrapped.original.(*testInitObject).initialized {
+		t.Error("initial func not called")
+		return
+	}
+
+	if obj2, err = factory.GetObject("initObj"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj2 != obj {
+		t.Error("singleton should cache the replaced object")
+		return
+	}
+
+	expected := "before:testObjBName,after:testObjBName,before:initObj,after:initObj"
+	if strings.Join(processor.calls, ",") != expected {
+		t.Errorf("bad post processor calls: %v", processor.calls)
+		return
+	}
+}
+
+type testDefinitionPostProcessor struct {
+	processed []string
+}
+
+func (p *testDefinitionPostProcessor) PostProcessDefinitions(defs []*ObjectDefinition) (err error) {
+	for _, def := range defs {
+		p.processed = append(p.processed, def.Name())
+
+		if def.Type() == reflect.TypeOf(testObjectB{}) {
+			if err = def.Apply(DefOptOfScope(Singleton)); err != nil {
+				return
+			}
+		}
+
+		if def.Type() == reflect.TypeOf(testObject{}) {
+			if err = def.Apply(DefOptOfObjectRef("ObjB", "testObjBName")); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func TestClassicFactoryOfDefinitionPostProcessor(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	processor := &testDefinitionPostProcessor{}
+	if err = factory.RegisterDefinitionPostProcessor(processor); err != nil {
+		t.Error(err)
+		return
+	}
+
+	created := 0
+	newB := func(opts Options) (v interface{}, err error) {
+		created++
+		return &testObjectB{BValue: "VB"}, nil
+	}
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(processor.processed) != 0 {
+		t.Error("definition post processor should not run before start")
+		return
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !factory.IsSingleton("testObjBName") || created != 1 {
+		t.Error("scope should be rewritten to singleton and created by start")
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var obj interface{}
+	if obj, err = factory.GetObject("testObjName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if obj.(*testObject).ObjB == nil || created != 1 {
+		t.Error("definition registered after start should be post processed")
+		return
+	}
+
+	if strings.Join(processor.processed, ",") != "testObjBName,testObjName" {
+		t.Errorf("every definition should be processed once, got: %v", processor.processed)
+		return
+	}
+}
+
+func TestClassicFactoryOfKeyedSingleton(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	newB := func(opts Options) (v interface{}, err error) {
+		b := &testObjectB{}
+		_, err = opts.Get("endpoint", &b.BValue)
+		return b, err
+	}
+
+	if err = factory.Define("client", Singleton, "testObjectB",
+		DefOptOfNewObjectFunc(newB),
+		DefOptOfKeyedSingleton(),
+		DefOptOfDefaultOptions(Options{"timeout": 1})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("client#1", Singleton, "testObjectB"); !ErrBadObjectDefinitionName.IsEqual(err) {
+		t.Errorf("the name containing the separator of instance key should fail, got: %v", err)
+		return
+	}
+
+	var a1, a2, b interface{}
+	if a1, err = factory.GetObject("client", Options{"endpoint": "a", "timeout": 1}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if a2, err = factory.GetObject("client", Options{"endpoint": "a"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if b, err = factory.GetObject("client", Options{"endpoint": "b"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if a1 != a2 || a1 == b || b.(*testObjectB).BValue != "b" {
+		t.Error("keyed singleton should cache one instance per effective options")
+		return
+	}
+
+	var keys []string
+	if keys, err = factory.SingletonKeys("client"); err != nil || len(keys) != 2 {
+		t.Errorf("keyed singleton should have 2 keys, got: %v, %v", keys, err)
+		return
+	}
+
+	if err = factory.EvictSingleton("client", keys[0]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if keys, err = factory.SingletonKeys("client"); err != nil || len(keys) != 1 {
+		t.Errorf("evicted key should be removed, got: %v, %v", keys, err)
+		return
+	}
+
+	if err = factory.EvictSingleton("client"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if keys, _ = factory.SingletonKeys("client"); len(keys) != 0 {
+		t.Errorf("all keys should be evicted, got: %v", keys)
+		return
+	}
+
+	var a3 interface{}
+	if a3, err = factory.GetObject("client", Options{"endpoint": "a"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if a3 == a1 {
+		t.Error("evicted singleton should be created again")
+		return
+	}
+}
+
+func TestClassicFactoryOfPooled(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	created := 0
+	newB := func(opts Options) (v interface{}, err error) {
+		created++
+		return &testObjectB{BValue: "VB"}, nil
+	}
+
+	valid := func(obj interface{}) bool {
+		return obj.(*testObjectB).BValue == "VB"
+	}
+
+	if err = factory.Define("parser", Pooled, "testObjectB",
+		DefOptOfNewObjectFunc(newB),
+		DefOptOfPool(PoolConfig{MinSize: 1, MaxSize: 2, Validate: valid})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var destroyed []interface{}
+	factory.Subscribe(func(event Event) {
+		destroyed = append(destroyed, event.Instance.Instance())
+	}, SubscribeOptOfTypes(InstanceDestroyed))
+
+	if _, err = factory.GetObject("parser"); !ErrPooledObjectShouldBorrow.IsEqual(err) {
+		t.Errorf("pooled object should not be got by GetObject, got: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+
+	var o1, o2 interface{}
+	if o1, err = factory.Borrow(ctx, "parser"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if o2, err = factory.Borrow(ctx, "parser"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if o1 == o2 || created != 2 {
+		t.Error("pool should create objects until max size")
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if _, err = factory.Borrow(timeoutCtx, "parser"); !ErrPoolExhausted.IsEqual(err) {
+		t.Errorf("exhausted pool should block until ctx done, got: %v", err)
+		return
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		factory.Return(o1)
+	}()
+
+	var o3 interface{}
+	if o3, err = factory.Borrow(ctx, "parser"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if o3 != o1 {
+		t.Error("returned object should be reused")
+		return
+	}
+
+	o3.(*testObjectB).BValue = "broken"
+	if err = factory.Return(o3); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Return(o3); !ErrObjectNotBorrowed.IsEqual(err) {
+		t.Errorf("object returned twice should fail, got: %v", err)
+		return
+	}
+
+	var o4 interface{}
+	if o4, err = factory.Borrow(ctx, "parser"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if o4 == o3 || created != 3 {
+		t.Error("invalid object should be discarded on borrow")
+		return
+	}
+
+	if len(destroyed) != 1 || destroyed[0] != o3 {
+		t.Errorf("the discarded object should be destroyed, got: %v", destroyed)
+		return
+	}
+}
+
+type testSliceObject struct {
+	Items []string
+}
+
+func TestClassicFactoryOfPooledNotComparable(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("slice", Pooled, "testSliceObject",
+		DefOptOfNewObjectFunc(func(Options) (interface{}, error) {
+			return testSliceObject{Items: []string{"a"}}, nil
+		}),
+		DefOptOfPool(PoolConfig{MaxSize: 1})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	destroyed := 0
+	factory.Subscribe(func(event Event) {
+		destroyed++
+	}, SubscribeOptOfTypes(InstanceDestroyed))
+
+	if _, err = factory.Borrow(context.Background(), "slice"); !ErrPooledObjectNotComparable.IsEqual(err) {
+		t.Errorf("the not comparable object should not be borrowed, got: %v", err)
+		return
+	}
+
+	if destroyed != 1 {
+		t.Errorf("the not comparable object should be destroyed, got: %d", destroyed)
+		return
+	}
+}
+
+func TestClassicFactoryOfPooledSlowCreation(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	if err = factory.Define("slow", Pooled, "testObjectB",
+		DefOptOfNewObjectFunc(func(Options) (interface{}, error) {
+			<-release
+			return &testObjectB{}, nil
+		}),
+		DefOptOfPool(PoolConfig{MinSize: 1, MaxSize: 1})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("fast", Pooled, "testObjectB", DefOptOfPool(PoolConfig{MaxSize: 1})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	go factory.Borrow(context.Background(), "slow")
+
+	// wait the slow pool in creation
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := factory.Borrow(context.Background(), "fast")
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Error("the creation of slow pool should not block the other pools")
+		return
+	}
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestObjectPoolEvictIdle(t *testing.T) {
+
+	var err error
+
+	def := &ObjectDefinition{name: "pool", poolConfig: PoolConfig{MinSize: 1, MaxSize: 3, IdleTimeout: time.Millisecond}}
+
+	var pool *objectPool
+	destroyed := 0
+	destroy := func(interface{}) { destroyed++ }
+
+	if pool, err = newObjectPool(context.Background(), def, func(context.Context) (interface{}, error) { return &testObjectB{}, nil }, destroy); err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := context.Background()
+
+	var objs []interface{}
+	for i := 0; i < 3; i++ {
+		var obj interface{}
+		if obj, err = pool.borrow(ctx); err != nil {
+			t.Error(err)
+			return
+		}
+		objs = append(objs, obj)
+	}
+
+	for _, obj := range objs {
+		pool.giveBack(obj)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	pool.locker.Lock()
+	evicted := pool.evictIdle()
+	pool.locker.Unlock()
+
+	pool.destroyAll(evicted)
+
+	if pool.idleCount() != 1 {
+		t.Errorf("idle objects should be evicted to min size, got: %d", pool.idleCount())
+		return
+	}
+
+	if destroyed != 2 {
+		t.Errorf("the evicted objects should be destroyed, got: %d", destroyed)
+		return
+	}
+}
+
+func TestClassicFactoryOfCreationTimeout(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	newSlowObjectB := func(ctx context.Context, opts Options) (v interface{}, err error) {
+		select {
+		case <-time.After(time.Second):
+			return &testObjectB{BValue: "slow"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err = factory.Define("slowObjB", Prototype, "testObjectB",
+		DefOptOfNewObjectFuncCtx(newSlowObjectB),
+		DefOptOfCreationTimeout(10*time.Millisecond)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject",
+		DefOptOfNewObjectFunc(newTestObject),
+		DefOptOfObjectRef("ObjC.CValue", "slowObjB")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = factory.GetObject("testObjName")
+	if !ErrObjectCreationTimeout.IsEqual(err) {
+		t.Errorf("creation should be timeout, got: %v", err)
+		return
+	}
+
+	if !strings.Contains(err.Error(), "slowObjB") || !strings.Contains(err.Error(), "testObjName -> ObjC.CValue -> slowObjB") {
+		t.Errorf("timeout error should contain the definition and path, got: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err = factory.GetObjectCtx(ctx, "testObjName"); !ErrObjectCreationCanceled.IsEqual(err) {
+		t.Errorf("creation should be canceled, got: %v", err)
+		return
+	}
+
+	if _, err = factory.GetObjectCtx(context.Background(), "testObjBName"); err == nil {
+		t.Error("not exist definition should fail")
+		return
+	}
+}
+
+type testLateObject struct {
+	closed chan struct{}
+}
+
+func (p *testLateObject) Close() {
+	close(p.closed)
+}
+
+func TestClassicFactoryOfCreationTimeoutDestroyLate(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	late := &testLateObject{closed: make(chan struct{})}
+
+	// the legacy constructor ignores the ctx
+	newLateObject := func(opts Options) (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return late, nil
+	}
+
+	if err = factory.Define("late", Prototype, "testLateObject",
+		DefOptOfNewObjectFunc(newLateObject),
+		DefOptOfDestroyFunc("Close"),
+		DefOptOfCreationTimeout(10*time.Millisecond)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("late"); !ErrObjectCreationTimeout.IsEqual(err) {
+		t.Errorf("creation should be timeout, got: %v", err)
+		return
+	}
+
+	select {
+	case <-late.closed:
+	case <-time.After(time.Second):
+		t.Error("the object created after timeout should be destroyed")
+		return
+	}
+}
+
+func TestClassicFactoryOfResolutionError(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("objC", Prototype, "testObjectC", DefOptOfObjectRef("CValue", "hub")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject",
+		DefOptOfNewObjectFunc(newTestObject),
+		DefOptOfObjectRef("ObjC.CValue", "objC")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = factory.GetObject("testObjName")
+
+	var resolutionErr *ResolutionError
+	if !errors.As(err, &resolutionErr) {
+		t.Errorf("error should be ResolutionError, got: %v", err)
+		return
+	}
+
+	if formatResolutionPath(resolutionErr.Path) != "testObjName -> ObjC.CValue -> objC -> CValue" || resolutionErr.Definition != "objC" {
+		t.Errorf("bad resolution path: %v", err)
+		return
+	}
+
+	if !ErrObjectDefintionNotExist.IsEqual(err) || !errors.Is(err, ErrObjectDefintionNotExist.New()) {
+		t.Errorf("error code should be ErrObjectDefintionNotExist, got: %v", err)
+		return
+	}
+
+	var data []byte
+	if data, err = json.Marshal(resolutionErr); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var logged struct {
+		Code       uint64
+		Definition string
+		Path       []ResolutionStep
+	}
+
+	if err = json.Unmarshal(data, &logged); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if logged.Code != 1003 || logged.Definition != "objC" || len(logged.Path) != 3 {
+		t.Errorf("bad json of resolution error: %s", data)
+		return
+	}
+
+	cause := errors.New("connect refused")
+
+	if err = factory.Define("badObjB", Prototype, "testObjectB", DefOptOfNewObjectFunc(func(Options) (interface{}, error) { return nil, cause })); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = factory.GetObject("badObjB")
+	if !errors.Is(err, cause) || !ErrCouldNotResolveObject.IsEqual(err) {
+		t.Errorf("error should wrap the cause, got: %v", err)
+		return
+	}
+}
+
+func TestClassicFactoryOfConcurrentSingleton(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var created int32
+	newObject := func(opts Options) (interface{}, error) {
+		atomic.AddInt32(&created, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &testObjectB{}, nil
+	}
+
+	if err = factory.Define("singleton", Singleton, "testObjectB", DefOptOfNewObjectFunc(newObject)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("keyed", Singleton, "testObjectB", DefOptOfNewObjectFunc(newObject), DefOptOfKeyedSingleton()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var createdEvents int32
+	factory.Subscribe(func(event Event) {
+		atomic.AddInt32(&createdEvents, 1)
+	}, SubscribeOptOfTypes(InstanceCreated))
+
+	for _, name := range []string{"singleton", "keyed"} {
+		objs := make([]interface{}, 16)
+		errs := make([]error, 16)
+
+		wg := sync.WaitGroup{}
+		for i := range objs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				objs[i], errs[i] = factory.GetObject(name, Options{"id": "1"})
+			}(i)
+		}
+		wg.Wait()
+
+		for i := range objs {
+			if errs[i] != nil {
+				t.Error(errs[i])
+				return
+			}
+
+			if objs[i] != objs[0] {
+				t.Errorf("the concurrent resolutions of %s should get the same instance", name)
+				return
+			}
+		}
+	}
+
+	if created != 2 || createdEvents != 2 {
+		t.Errorf("one instance should be created for each singleton, created: %d, events: %d", created, createdEvents)
+		return
+	}
+}
+
+func TestClassicFactoryOfConcurrentCyclicSingleton(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	slow := func(obj interface{}) NewObjectFunc {
+		return func(Options) (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return obj, nil
+		}
+	}
+
+	if err = factory.Define("a", Singleton, "testCyclicA", DefOptOfNewObjectFunc(slow(&testCyclicA{})), DefOptOfObjectRef("B", "b")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("b", Singleton, "testCyclicB", DefOptOfNewObjectFunc(slow(&testCyclicB{})), DefOptOfObjectRef("A", "a")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	objs := make([]interface{}, 2)
+	errs := make([]error, 2)
+
+	done := make(chan struct{})
+	go func() {
+		wg := sync.WaitGroup{}
+		for i, name := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				objs[i], errs[i] = factory.GetObject(name)
+			}(i, name)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("the concurrent resolutions of cyclic singletons should not deadlock")
+		return
+	}
+
+	for _, err = range errs {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	a, b := objs[0].(*testCyclicA), objs[1].(*testCyclicB)
+	if a.B != b || b.A != a {
+		t.Error("the cyclic singletons should ref each other")
+		return
+	}
+}
+
+func TestClassicFactoryOfRefTypeNotMatch(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	if err = factory.Define("testObjBName", Singleton, "testObjectB"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("consumer", Prototype, "testStoreConsumer", DefOptOfObjectRef("Store", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("consumer"); !ErrRefTypeNotMatch.IsEqual(err) {
+		t.Errorf("ref not implement the interface field should fail, got: %v", err)
+		return
+	}
+}
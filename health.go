@@ -0,0 +1,180 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogap/errors"
+	"sync"
+	"time"
+)
+
+// DefaultHealthTimeout is the timeout of each health check without DefOptOfHealthTimeout
+const DefaultHealthTimeout = 5 * time.Second
+
+// HealthChecker is implemented by the objects reporting their health, the
+// singletons implementing it are checked by Factory.Health
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+type HealthStatus int
+
+const (
+	HealthUp HealthStatus = iota
+	// HealthDegraded means some checks of non-critical definitions failed
+	HealthDegraded
+	// HealthDown means some checks of critical definitions failed
+	HealthDown
+)
+
+func (p HealthStatus) String() string {
+	switch p {
+	case HealthUp:
+		return "up"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDown:
+		return "down"
+	}
+
+	return "unknown"
+}
+
+type HealthCheck struct {
+	Definition string
+	Key        string
+	Critical   bool
+	Duration   time.Duration
+	Err        error
+}
+
+type HealthReport struct {
+	Status HealthStatus
+	Checks []HealthCheck
+}
+
+// Ready returns false if any check of critical definition failed
+func (p HealthReport) Ready() bool {
+	return p.Status != HealthDown
+}
+
+// DefOptOfCritical make the factory down if the health check of the definition failed
+func DefOptOfCritical() DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.critical = true
+		return
+	}}
+}
+
+// DefOptOfHealthTimeout limit the time of health check of the definition
+func DefOptOfHealthTimeout(timeout time.Duration) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.healthTimeout = timeout
+		return
+	}}
+}
+
+func (p *ObjectDefinition) IsCritical() bool {
+	return p.critical
+}
+
+func (p *ObjectDefinition) HealthTimeout() time.Duration {
+	if p.healthTimeout <= 0 {
+		return DefaultHealthTimeout
+	}
+	return p.healthTimeout
+}
+
+// Health run the checks of the singletons implementing HealthChecker in parallel,
+// the checks are in the order of Instances
+func (p *ClassicFactory) Health(ctx context.Context) (report HealthReport) {
+
+	var checkers []*ObjectInstance
+	var objs []HealthChecker
+
+	for _, objIns := range p.Instances() {
+		p.insLocker.RLock()
+		obj, ready := objIns.object, objIns.ready
+		p.insLocker.RUnlock()
+
+		if !ready {
+			continue
+		}
+
+		target, _ := p.undecorate(objIns.definition, obj)
+		checker, ok := target.(HealthChecker)
+
+		if ok {
+			checkers = append(checkers, objIns)
+			objs = append(objs, checker)
+		}
+	}
+
+	report.Checks = make([]HealthCheck, len(checkers))
+
+	wg := sync.WaitGroup{}
+
+	for i := range checkers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = checkHealth(ctx, checkers[i], objs[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Err == nil {
+			continue
+		}
+
+		if check.Critical {
+			report.Status = HealthDown
+		} else if report.Status == HealthUp {
+			report.Status = HealthDegraded
+		}
+	}
+
+	return
+}
+
+func checkHealth(ctx context.Context, objIns *ObjectInstance, checker HealthChecker) (check HealthCheck) {
+
+	def := objIns.definition
+
+	check = HealthCheck{
+		Definition: def.Name(),
+		Key:        objIns.Key(),
+		Critical:   def.IsCritical(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, def.HealthTimeout())
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- ErrHealthCheckFailed.New(errors.Params{"name": def.Name(), "err": fmt.Sprintf("panic: %v", r)})
+			}
+		}()
+
+		done <- checker.Health(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			check.Err = ErrHealthCheckFailed.New(errors.Params{"name": def.Name(), "err": err})
+		}
+	case <-ctx.Done():
+		check.Err = ErrHealthCheckTimeout.New(errors.Params{"name": def.Name()})
+	}
+
+	check.Duration = time.Since(start)
+
+	return
+}
@@ -0,0 +1,116 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testHealthObject struct {
+	err   error
+	delay time.Duration
+}
+
+func (p *testHealthObject) Health(ctx context.Context) error {
+	select {
+	case <-time.After(p.delay):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func init() {
+	RegisterModel((*testHealthObject)(nil), "testHealthObject")
+}
+
+func TestClassicFactoryOfHealth(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	newHealthObject := func(err error, delay time.Duration) NewObjectFunc {
+		return func(opts Options) (interface{}, error) {
+			return &testHealthObject{err: err, delay: delay}, nil
+		}
+	}
+
+	defs := []struct {
+		name  string
+		model string
+		opts  []DefinitionOption
+	}{
+		{"db", "testHealthObject", []DefinitionOption{DefOptOfNewObjectFunc(newHealthObject(nil, 0)), DefOptOfCritical()}},
+		{"cache", "testHealthObject", []DefinitionOption{DefOptOfNewObjectFunc(newHealthObject(errors.New("cache unavailable"), 0))}},
+		{"mq", "testHealthObject", []DefinitionOption{DefOptOfNewObjectFunc(newHealthObject(nil, time.Second)), DefOptOfHealthTimeout(50 * time.Millisecond)}},
+		{"testObjBName", "testObjectB", []DefinitionOption{DefOptOfNewObjectFunc(newTestObjectB)}},
+	}
+
+	for _, def := range defs {
+		if err = factory.Define(def.name, Singleton, def.model, def.opts...); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err = factory.Start(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	start := time.Now()
+	report := factory.Health(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("health checks should run in parallel with timeout, elapsed: %s", elapsed)
+		return
+	}
+
+	if report.Status != HealthDegraded || !report.Ready() {
+		t.Errorf("status should be degraded and ready, got: %s", report.Status)
+		return
+	}
+
+	if len(report.Checks) != 3 {
+		t.Errorf("checks should be 3, got: %d", len(report.Checks))
+		return
+	}
+
+	expected := []struct {
+		name     string
+		critical bool
+		timeout  bool
+		failed   bool
+	}{
+		{"cache", false, false, true},
+		{"db", true, false, false},
+		{"mq", false, true, true},
+	}
+
+	for i, e := range expected {
+		check := report.Checks[i]
+
+		if check.Definition != e.name || check.Critical != e.critical || (check.Err != nil) != e.failed {
+			t.Errorf("unexpected check of %s: %+v", e.name, check)
+			return
+		}
+
+		if e.timeout && !ErrHealthCheckTimeout.IsEqual(check.Err) {
+			t.Errorf("check of %s should timeout, got: %v", e.name, check.Err)
+			return
+		}
+	}
+
+	if err = factory.Redefine("db", Singleton, "testHealthObject",
+		DefOptOfNewObjectFunc(newHealthObject(errors.New("connection refused"), 0)), DefOptOfCritical()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if report = factory.Health(context.Background()); report.Status != HealthDown || report.Ready() {
+		t.Errorf("status should be down, got: %s", report.Status)
+		return
+	}
+}
@@ -0,0 +1,312 @@
+package factory
+
+import (
+	"fmt"
+	"github.com/gogap/errors"
+	"path"
+	"reflect"
+	"sync"
+)
+
+var (
+	proxiesLocker sync.Mutex
+	proxies       = make(map[reflect.Type]NewProxyFunc)
+)
+
+// InvocationHandler is embedded by the proxy of interface, every method of the
+// proxy forwards its args to Invoke and returns the results, the variadic args
+// are passed as one slice, the proxy could be generated by cmd/factory-proxy:
+//
+//	type engineProxy struct {
+//		factory.InvocationHandler
+//	}
+//
+//	func (p *engineProxy) Start(ctx context.Context) (int, error) {
+//		results := p.Invoke("Start", ctx)
+//		power, _ := results[0].(int)
+//		err, _ := results[1].(error)
+//		return power, err
+//	}
+type InvocationHandler interface {
+	Invoke(method string, args ...interface{}) []interface{}
+
+	target() interface{}
+}
+
+type NewProxyFunc func(handler InvocationHandler) interface{}
+
+// Interceptor is called around the method of proxy, it calls inv.Proceed to
+// invoke the next interceptor or the target method. The returned error replaces
+// the last result of method, the method without error result panics with it
+type Interceptor func(inv *Invocation) error
+
+// MethodMatcher is the pointcut selecting the methods of proxy interface
+type MethodMatcher func(method string) bool
+
+// MatchMethods match the method names by the patterns of path.Match, such as "Get*"
+func MatchMethods(patterns ...string) MethodMatcher {
+	return func(method string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, method); matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func MatchAllMethods() MethodMatcher {
+	return func(method string) bool {
+		return true
+	}
+}
+
+type advice struct {
+	matcher      MethodMatcher
+	interceptors []Interceptor
+}
+
+// RegisterProxy register the proxy constructor of interface, the iface should
+// be the nil pointer of interface, such as (*Engine)(nil)
+func RegisterProxy(iface interface{}, newProxy NewProxyFunc) (err error) {
+
+	var typ reflect.Type
+	if typ, err = interfaceType(iface); err != nil {
+		return
+	}
+
+	proxiesLocker.Lock()
+	proxies[typ] = newProxy
+	proxiesLocker.Unlock()
+
+	return
+}
+
+func getProxy(typ reflect.Type) (newProxy NewProxyFunc, exist bool) {
+	proxiesLocker.Lock()
+	newProxy, exist = proxies[typ]
+	proxiesLocker.Unlock()
+
+	return
+}
+
+func interfaceType(iface interface{}) (typ reflect.Type, err error) {
+	typ = reflect.TypeOf(iface)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Interface {
+		err = ErrBadProxyInterface.New(errors.Params{"interface": fmt.Sprintf("%T", iface)})
+		return
+	}
+
+	return
+}
+
+// DefOptOfProxy make the objects of definition replaced by the proxy of iface,
+// which is the nil pointer of interface registered by RegisterProxy
+func DefOptOfProxy(iface interface{}) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+
+		var typ reflect.Type
+		if typ, err = interfaceType(iface); err != nil {
+			return
+		}
+
+		if !od.typ.Implements(typ) && !reflect.PtrTo(od.typ).Implements(typ) {
+			err = ErrProxyInterfaceNotImplemented.New(errors.Params{"name": od.name, "interface": typ.String()})
+			return
+		}
+
+		od.proxyType = typ
+		return
+	}}
+}
+
+// DefOptOfInterceptor add the interceptors around the methods matched of the proxy,
+// the interceptors of definition are called in the order added
+func DefOptOfInterceptor(matcher MethodMatcher, interceptors ...Interceptor) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.advices = append(od.advices, advice{matcher: matcher, interceptors: interceptors})
+		return
+	}}
+}
+
+func (p *ObjectDefinition) ProxyType() reflect.Type {
+	return p.proxyType
+}
+
+// Invocation is the call of proxy method, the interceptors could change the
+// Args before Proceed, and the Results after it
+type Invocation struct {
+	Definition *ObjectDefinition
+	Target     interface{}
+	Method     string
+	Args       []interface{}
+	Results    []interface{}
+
+	method       reflect.Value
+	interceptors []Interceptor
+	index        int
+}
+
+// Proceed invoke the next interceptor or the target method, it could be called
+// more than once, such as retry
+func (p *Invocation) Proceed() (err error) {
+
+	if p.index < len(p.interceptors) {
+		interceptor := p.interceptors[p.index]
+
+		p.index++
+		defer func() { p.index-- }()
+
+		return interceptor(p)
+	}
+
+	methodType := p.method.Type()
+
+	in := make([]reflect.Value, len(p.Args))
+	for i, arg := range p.Args {
+		argType := methodType.In(methodType.NumIn() - 1)
+		if i < methodType.NumIn()-1 || !methodType.IsVariadic() {
+			argType = methodType.In(i)
+		}
+
+		if arg == nil {
+			in[i] = reflect.Zero(argType)
+		} else {
+			in[i] = reflect.ValueOf(arg)
+		}
+	}
+
+	var outs []reflect.Value
+	if methodType.IsVariadic() {
+		outs = p.method.CallSlice(in)
+	} else {
+		outs = p.method.Call(in)
+	}
+
+	p.Results = make([]interface{}, len(outs))
+	for i, out := range outs {
+		p.Results[i] = out.Interface()
+	}
+
+	return p.Err()
+}
+
+// Err returns the last result if it is error
+func (p *Invocation) Err() error {
+	if len(p.Results) == 0 {
+		return nil
+	}
+
+	err, _ := p.Results[len(p.Results)-1].(error)
+	return err
+}
+
+type invocationHandler struct {
+	def     *ObjectDefinition
+	obj     interface{}
+	objVal  reflect.Value
+	methods map[string][]Interceptor
+}
+
+func (p *invocationHandler) Invoke(method string, args ...interface{}) []interface{} {
+
+	inv := &Invocation{
+		Definition:   p.def,
+		Target:       p.obj,
+		Method:       method,
+		Args:         args,
+		method:       p.objVal.MethodByName(method),
+		interceptors: p.methods[method],
+	}
+
+	if !inv.method.IsValid() {
+		panic(fmt.Sprintf("factory: method %s of proxy not exist, name: %s", method, p.def.Name()))
+	}
+
+	err := inv.Proceed()
+
+	methodType := inv.method.Type()
+
+	if inv.Results == nil {
+		inv.Results = make([]interface{}, methodType.NumOut())
+		for i := range inv.Results {
+			inv.Results[i] = reflect.Zero(methodType.Out(i)).Interface()
+		}
+	}
+
+	if numOut := methodType.NumOut(); numOut > 0 && methodType.Out(numOut-1) == errorType {
+		inv.Results[numOut-1] = err
+	} else if err != nil {
+		panic(err)
+	}
+
+	return inv.Results
+}
+
+func (p *invocationHandler) target() interface{} {
+	return p.obj
+}
+
+// ProxyTarget returns the object behind the proxy, or obj itself if it is not proxy
+func ProxyTarget(obj interface{}) interface{} {
+	if handler, ok := obj.(interface{ target() interface{} }); ok {
+		return handler.target()
+	}
+	return obj
+}
+
+// proxyPostProcessor replace the object by the proxy after all the post
+// processors, so the proxy is the object handed out
+type proxyPostProcessor struct{}
+
+func (proxyPostProcessor) BeforeInit(obj interface{}, def *ObjectDefinition) (interface{}, error) {
+	return obj, nil
+}
+
+func (proxyPostProcessor) AfterInit(obj interface{}, def *ObjectDefinition) (retObj interface{}, err error) {
+
+	retObj = obj
+
+	if def.proxyType == nil {
+		if len(def.advices) > 0 {
+			err = ErrInterceptorWithoutProxy.New(errors.Params{"name": def.Name()})
+		}
+		return
+	}
+
+	newProxy, exist := getProxy(def.proxyType)
+	if !exist {
+		err = ErrProxyNotRegistered.New(errors.Params{"name": def.Name(), "interface": def.proxyType.String()})
+		return
+	}
+
+	objVal := reflect.ValueOf(obj)
+	if !objVal.IsValid() || !objVal.Type().Implements(def.proxyType) {
+		err = ErrProxyInterfaceNotImplemented.New(errors.Params{"name": def.Name(), "interface": def.proxyType.String()})
+		return
+	}
+
+	handler := &invocationHandler{
+		def:     def,
+		obj:     obj,
+		objVal:  objVal,
+		methods: make(map[string][]Interceptor),
+	}
+
+	for i := 0; i < def.proxyType.NumMethod(); i++ {
+		method := def.proxyType.Method(i).Name
+		for _, advice := range def.advices {
+			if advice.matcher == nil || advice.matcher(method) {
+				handler.methods[method] = append(handler.methods[method], advice.interceptors...)
+			}
+		}
+	}
+
+	retObj = newProxy(handler)
+
+	return
+}
@@ -0,0 +1,168 @@
+package factory
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testGreeter interface {
+	Greet(name string) (string, error)
+	Count() int
+}
+
+type testGreeterProxy struct {
+	InvocationHandler
+}
+
+func (p *testGreeterProxy) Greet(name string) (string, error) {
+	results := p.Invoke("Greet", name)
+	greeting, _ := results[0].(string)
+	err, _ := results[1].(error)
+	return greeting, err
+}
+
+func (p *testGreeterProxy) Count() int {
+	results := p.Invoke("Count")
+	count, _ := results[0].(int)
+	return count
+}
+
+type testGreeterObject struct {
+	failures int
+	count    int
+	closed   bool
+}
+
+func (p *testGreeterObject) Greet(name string) (string, error) {
+	p.count++
+
+	if p.failures > 0 {
+		p.failures--
+		return "", errors.New("temporary failure")
+	}
+
+	return "hello " + name, nil
+}
+
+func (p *testGreeterObject) Count() int {
+	return p.count
+}
+
+func (p *testGreeterObject) Close() {
+	p.closed = true
+}
+
+type testGreeterConsumer struct {
+	Greeter testGreeter
+}
+
+func init() {
+	RegisterModel((*testGreeterObject)(nil), "testGreeterObject")
+	RegisterModel((*testGreeterConsumer)(nil), "testGreeterConsumer")
+
+	RegisterProxy((*testGreeter)(nil), func(handler InvocationHandler) interface{} {
+		return &testGreeterProxy{handler}
+	})
+}
+
+func TestClassicFactoryOfInterceptor(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var calls []string
+
+	logging := func(inv *Invocation) error {
+		calls = append(calls, "before "+inv.Method)
+		err := inv.Proceed()
+		calls = append(calls, "after "+inv.Method)
+		return err
+	}
+
+	retry := func(inv *Invocation) (err error) {
+		for i := 0; i < 3; i++ {
+			if err = inv.Proceed(); err == nil {
+				return
+			}
+		}
+		return
+	}
+
+	authorization := func(inv *Invocation) error {
+		if inv.Args[0] == "guest" {
+			return errors.New("forbidden")
+		}
+		return inv.Proceed()
+	}
+
+	if err = factory.Define("greeter", Singleton, "testGreeterObject",
+		DefOptOfNewObjectFunc(func(opts Options) (interface{}, error) {
+			return &testGreeterObject{failures: 2}, nil
+		}),
+		DefOptOfProxy((*testGreeter)(nil)),
+		DefOptOfInterceptor(MatchAllMethods(), logging),
+		DefOptOfInterceptor(MatchMethods("Gr*"), authorization, retry),
+		DefOptOfDestroyFunc("Close"),
+	); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("consumer", Prototype, "testGreeterConsumer", DefOptOfObjectRef("Greeter", "greeter")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj, err := factory.GetObject("consumer")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	greeter := obj.(*testGreeterConsumer).Greeter
+
+	if _, ok := greeter.(*testGreeterProxy); !ok {
+		t.Errorf("the injected greeter should be proxy, got: %T", greeter)
+		return
+	}
+
+	greeting, err := greeter.Greet("gogap")
+	if err != nil || greeting != "hello gogap" {
+		t.Errorf("greet should be retried, got: %s, %v", greeting, err)
+		return
+	}
+
+	if count := greeter.Count(); count != 3 {
+		t.Errorf("the target should be called 3 times, got: %d", count)
+		return
+	}
+
+	if _, err = greeter.Greet("guest"); err == nil || err.Error() != "forbidden" {
+		t.Errorf("guest should be forbidden, got: %v", err)
+		return
+	}
+
+	if strings.Join(calls, ",") != "before Greet,after Greet,before Count,after Count,before Greet,after Greet" {
+		t.Errorf("unexpected calls: %v", calls)
+		return
+	}
+
+	target := ProxyTarget(greeter).(*testGreeterObject)
+
+	if err = factory.DestroySingleton("greeter"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !target.closed {
+		t.Error("the destroy func should be called on the target")
+		return
+	}
+
+	if err = factory.Define("badGreeter", Singleton, "testGreeterConsumer", DefOptOfProxy((*testGreeter)(nil))); !ErrProxyInterfaceNotImplemented.IsEqual(err) {
+		t.Errorf("define proxy of not implemented interface should fail, got: %v", err)
+		return
+	}
+}
@@ -0,0 +1,108 @@
+package factory
+
+import (
+	"sort"
+)
+
+type RefState int
+
+const (
+	RefResolved   RefState = 0
+	RefUnresolved RefState = 1
+	RefBroken     RefState = 2
+)
+
+func (p RefState) String() string {
+	switch p {
+	case RefResolved:
+		return "resolved"
+	case RefUnresolved:
+		return "unresolved"
+	case RefBroken:
+		return "broken"
+	}
+
+	return "unknown"
+}
+
+type RefInfo struct {
+	Field      string
+	Definition string
+	Members    []string
+	Optional   bool
+	Lazy       bool
+	State      RefState
+	Error      error
+}
+
+// GetRefs reports the refs of the definition and whether they could be resolved,
+// a missing optional ref is unresolved, a missing required ref is broken
+func (p *ClassicFactory) GetRefs(name string) (refs []RefInfo, err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.getObjDefinition(name); err != nil {
+		return
+	}
+
+	for _, fieldName := range removeDuplicatesInOrder(def.refsOrder) {
+
+		_, lazy := def.refsLazy[fieldName]
+
+		info := RefInfo{
+			Field:    fieldName,
+			Optional: def.refsOptional[fieldName],
+			Lazy:     lazy,
+			State:    RefResolved,
+		}
+
+		if collection, exist := def.refsCollection[fieldName]; exist {
+			for _, member := range p.getCollectionDefinitions(def, collection) {
+				info.Members = append(info.Members, member.Name())
+			}
+
+			refs = append(refs, info)
+			continue
+		}
+
+		refDef, resolveErr := p.refResolver(def, fieldName)()
+
+		switch {
+		case resolveErr == nil:
+			info.Definition = refDef.Name()
+		case info.Optional && isMissingDefinitionError(resolveErr):
+			info.Definition = def.refs[fieldName]
+			info.State = RefUnresolved
+		default:
+			info.Definition = def.refs[fieldName]
+			info.State = RefBroken
+			info.Error = resolveErr
+		}
+
+		refs = append(refs, info)
+	}
+
+	return
+}
+
+// Definitions returns all the registered definitions, including the inactive ones
+func (p *ClassicFactory) Definitions() []*ObjectDefinition {
+	return p.getObjDefinitions()
+}
+
+// Instances returns the cached instances of singletons
+func (p *ClassicFactory) Instances() (instances []*ObjectInstance) {
+	p.insLocker.RLock()
+	for _, objIns := range p.objInstances {
+		instances = append(instances, objIns)
+	}
+	p.insLocker.RUnlock()
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].definition.Name() != instances[j].definition.Name() {
+			return instances[i].definition.Name() < instances[j].definition.Name()
+		}
+		return instances[i].Key() < instances[j].Key()
+	})
+
+	return
+}
@@ -0,0 +1,88 @@
+package factory
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gogap/errors"
+	"sort"
+	"strings"
+)
+
+const keyedInstanceSeparator = "#"
+
+// hashOptions returns the canonical hash of options, the json encoding
+// sorts the keys of maps
+func hashOptions(opts Options) (key string, err error) {
+	if opts == nil {
+		opts = Options{}
+	}
+
+	var data []byte
+	if data, err = json.Marshal(opts); err != nil {
+		err = ErrCouldNotHashOptions.New(errors.Params{"err": err})
+		return
+	}
+
+	sum := sha1.Sum(data)
+	key = hex.EncodeToString(sum[:])
+
+	return
+}
+
+func keyedInstanceKey(name, key string) string {
+	return name + keyedInstanceSeparator + key
+}
+
+// SingletonKeys returns the option keys of cached instances of the keyed singleton
+func (p *ClassicFactory) SingletonKeys(name string) (keys []string, err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.getObjDefinition(name); err != nil {
+		return
+	}
+
+	prefix := keyedInstanceKey(def.Name(), "")
+
+	p.insLocker.RLock()
+	for instanceKey := range p.objInstances {
+		if strings.HasPrefix(instanceKey, prefix) {
+			keys = append(keys, strings.TrimPrefix(instanceKey, prefix))
+		}
+	}
+	p.insLocker.RUnlock()
+
+	sort.Strings(keys)
+
+	return
+}
+
+// EvictSingleton destroy the cached instances of the singleton, for the keyed
+// singleton, only the instances of keys are destroyed if keys are given
+func (p *ClassicFactory) EvictSingleton(name string, keys ...string) (err error) {
+
+	var def *ObjectDefinition
+	if def, err = p.getObjDefinition(name); err != nil {
+		return
+	}
+
+	if !def.IsKeyedSingleton() {
+		keys = []string{""}
+	} else if len(keys) == 0 {
+		if keys, err = p.SingletonKeys(name); err != nil {
+			return
+		}
+	}
+
+	var instanceKeys []string
+	for _, key := range keys {
+		instanceKey := def.Name()
+		if def.IsKeyedSingleton() {
+			instanceKey = keyedInstanceKey(def.Name(), key)
+		}
+
+		instanceKeys = append(instanceKeys, instanceKey)
+	}
+
+	return p.destroy(p.detachInstanceKeys(instanceKeys...))
+}
@@ -0,0 +1,96 @@
+package factory
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// MaskedValue replaces the values of secret keys
+const MaskedValue = "******"
+
+// DefaultSecretPatterns are the case-insensitive substrings of the option and
+// property keys whose values are masked in logs
+var DefaultSecretPatterns = []string{"password", "secret", "token", "credential", "key"}
+
+// FactoryOptOfLogger log the wiring decisions at debug level, such as the definitions
+// registered, the constructors chosen, the fields injected and the effective options
+func FactoryOptOfLogger(handler slog.Handler) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		if handler != nil {
+			p.logger = slog.New(handler)
+		}
+	}}
+}
+
+// FactoryOptOfLogSecretPatterns replace the DefaultSecretPatterns of the logged options
+func FactoryOptOfLogSecretPatterns(patterns ...string) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		p.logSecretPatterns = patterns
+	}}
+}
+
+// FactoryOptOfLogFilter only log the definitions accepted by the filter
+func FactoryOptOfLogFilter(filter func(def *ObjectDefinition) bool) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		p.logFilter = filter
+	}}
+}
+
+func (p *ClassicFactory) debug(ctx context.Context, def *ObjectDefinition, msg string, attrs ...slog.Attr) {
+	if p.logger == nil || !p.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	if p.logFilter != nil && !p.logFilter(def) {
+		return
+	}
+
+	attrs = append([]slog.Attr{slog.String("definition", def.Name())}, attrs...)
+
+	if path := resolutionPathOf(ctx); len(path) > 0 {
+		attrs = append(attrs, slog.String("path", formatResolutionPath(path)))
+	}
+
+	p.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// MaskSecrets returns a copy of values, the values of the keys containing any of
+// the patterns case-insensitively are masked, and so are the nested maps
+func MaskSecrets(values map[string]interface{}, patterns []string) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	masked := make(map[string]interface{}, len(values))
+
+	for key, value := range values {
+		if isSecretKey(key, patterns) {
+			masked[key] = MaskedValue
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			masked[key] = MaskSecrets(v, patterns)
+		case Options:
+			masked[key] = MaskSecrets(v, patterns)
+		default:
+			masked[key] = value
+		}
+	}
+
+	return masked
+}
+
+func isSecretKey(key string, patterns []string) bool {
+	key = strings.ToLower(key)
+
+	for _, pattern := range patterns {
+		if strings.Contains(key, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+
+	return false
+}
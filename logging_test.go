@@ -0,0 +1,110 @@
+package factory
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassicFactoryOfLogger(t *testing.T) {
+
+	var err error
+
+	buf := bytes.NewBuffer(nil)
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	factory := NewClassicFactory(nil,
+		FactoryOptOfLogger(handler),
+		FactoryOptOfLogFilter(func(def *ObjectDefinition) bool {
+			return def.Name() != "testObjBName"
+		}),
+	)
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject", DefOptOfObjectRef("ObjB", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("testObjName", Options{"owner": "gogap"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	logs := buf.String()
+
+	for _, expected := range []string{
+		`msg="definition registered" definition=testObjName scope=prototype`,
+		`msg="constructor chosen" definition=testObjName constructor=newTypeInstance`,
+		`msg="field injected" definition=testObjName field=ObjB source=testObjBName`,
+		`msg="effective options" definition=testObjName options=map[owner:gogap]`,
+	} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("logs should contain %q, got:\n%s", expected, logs)
+			return
+		}
+	}
+
+	if strings.Contains(logs, "definition=testObjBName") {
+		t.Errorf("the filtered definition should not be logged, got:\n%s", logs)
+		return
+	}
+}
+
+func TestClassicFactoryOfLoggerCallback(t *testing.T) {
+
+	var err error
+
+	buf := bytes.NewBuffer(nil)
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	var factory *ClassicFactory
+	factory = NewClassicFactory(nil,
+		FactoryOptOfLogger(handler),
+		FactoryOptOfLogFilter(func(def *ObjectDefinition) bool {
+			// the filter could call back into the factory
+			return factory.ContainsObject(def.Name())
+		}),
+	)
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := factory.GetObject("testObjBName", Options{"owner": "gogap", "password": "123456"})
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Error("the log filter calling back into the factory should not deadlock")
+		return
+	}
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logs := buf.String()
+
+	if !strings.Contains(logs, `msg="constructor chosen" definition=testObjBName constructor=NewObjectFunc`) {
+		t.Errorf("the constructor should be logged, got:\n%s", logs)
+		return
+	}
+
+	if strings.Contains(logs, "123456") || !strings.Contains(logs, "password:"+MaskedValue) {
+		t.Errorf("the secret options should be masked, got:\n%s", logs)
+		return
+	}
+}
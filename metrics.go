@@ -0,0 +1,35 @@
+package factory
+
+import (
+	"time"
+)
+
+const (
+	MetricInstancesCreated     = "factory_instances_created_total"
+	MetricSingletonCacheHits   = "factory_singleton_cache_hits_total"
+	MetricSingletonCacheMisses = "factory_singleton_cache_misses_total"
+	MetricCreationFailures     = "factory_creation_failures_total"
+	MetricConstructorLatency   = "factory_constructor_duration_seconds"
+	MetricInjectionLatency     = "factory_injection_duration_seconds"
+)
+
+// MetricsSink receives the per-definition metrics of factory, it should be
+// safe for concurrent use
+type MetricsSink interface {
+	IncCounter(name string, definition string)
+	ObserveDuration(name string, definition string, duration time.Duration)
+}
+
+type nopMetricsSink struct{}
+
+func (nopMetricsSink) IncCounter(name string, definition string) {}
+
+func (nopMetricsSink) ObserveDuration(name string, definition string, duration time.Duration) {}
+
+func FactoryOptOfMetricsSink(sink MetricsSink) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		if sink != nil {
+			p.metrics = sink
+		}
+	}}
+}
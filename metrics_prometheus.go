@@ -0,0 +1,176 @@
+package factory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency histograms
+var DefaultLatencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+var metricHelps = map[string]string{
+	MetricInstancesCreated:     "The number of instances created.",
+	MetricSingletonCacheHits:   "The number of singletons got from the cache.",
+	MetricSingletonCacheMisses: "The number of singletons not in the cache.",
+	MetricCreationFailures:     "The number of failures of creating instances.",
+	MetricConstructorLatency:   "The latency of constructors in seconds.",
+	MetricInjectionLatency:     "The latency of injecting refs in seconds.",
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// PrometheusSink keeps the metrics in memory and writes them in the Prometheus
+// text exposition format, it is also the http.Handler of the metrics
+type PrometheusSink struct {
+	buckets []float64
+
+	locker     sync.Mutex
+	counters   map[string]map[string]uint64
+	histograms map[string]map[string]*histogram
+}
+
+// NewPrometheusSink create the sink with the latency buckets, DefaultLatencyBuckets if empty
+func NewPrometheusSink(buckets ...float64) *PrometheusSink {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusSink{
+		buckets:    buckets,
+		counters:   make(map[string]map[string]uint64),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+func (p *PrometheusSink) IncCounter(name string, definition string) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	counters, exist := p.counters[name]
+	if !exist {
+		counters = make(map[string]uint64)
+		p.counters[name] = counters
+	}
+
+	counters[definition]++
+}
+
+func (p *PrometheusSink) ObserveDuration(name string, definition string, duration time.Duration) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	histograms, exist := p.histograms[name]
+	if !exist {
+		histograms = make(map[string]*histogram)
+		p.histograms[name] = histograms
+	}
+
+	h, exist := histograms[definition]
+	if !exist {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		histograms[definition] = h
+	}
+
+	seconds := duration.Seconds()
+
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += seconds
+	h.count++
+}
+
+// Counter returns the value of the counter of the definition
+func (p *PrometheusSink) Counter(name string, definition string) uint64 {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	return p.counters[name][definition]
+}
+
+// WriteTo write the metrics in the Prometheus text exposition format
+func (p *PrometheusSink) WriteTo(w io.Writer) (n int64, err error) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	buf := bufio.NewWriter(w)
+
+	write := func(format string, args ...interface{}) {
+		if err != nil {
+			return
+		}
+
+		var written int
+		written, err = fmt.Fprintf(buf, format, args...)
+		n += int64(written)
+	}
+
+	for _, name := range sortedMetricNames(p.counters) {
+		write("# HELP %s %s\n# TYPE %s counter\n", name, metricHelps[name], name)
+
+		counters := p.counters[name]
+		for _, definition := range sortedMetricNames(counters) {
+			write("%s{definition=\"%s\"} %d\n", name, escapeLabelValue(definition), counters[definition])
+		}
+	}
+
+	for _, name := range sortedMetricNames(p.histograms) {
+		write("# HELP %s %s\n# TYPE %s histogram\n", name, metricHelps[name], name)
+
+		histograms := p.histograms[name]
+		for _, definition := range sortedMetricNames(histograms) {
+			h := histograms[definition]
+			label := escapeLabelValue(definition)
+
+			for i, bound := range p.buckets {
+				write("%s_bucket{definition=\"%s\",le=\"%s\"} %d\n", name, label, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+			}
+
+			write("%s_bucket{definition=\"%s\",le=\"+Inf\"} %d\n", name, label, h.count)
+			write("%s_sum{definition=\"%s\"} %s\n", name, label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+			write("%s_count{definition=\"%s\"} %d\n", name, label, h.count)
+		}
+	}
+
+	if err == nil {
+		err = buf.Flush()
+	}
+
+	return
+}
+
+func (p *PrometheusSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+func sortedMetricNames[V any](m map[string]V) (names []string) {
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
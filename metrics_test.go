@@ -0,0 +1,83 @@
+package factory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestClassicFactoryOfMetrics(t *testing.T) {
+
+	var err error
+
+	sink := NewPrometheusSink()
+
+	factory := NewClassicFactory(nil, FactoryOptOfMetricsSink(sink))
+
+	if err = factory.Define("testObjBName", Singleton, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject", DefOptOfObjectRef("ObjB", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("badObj", Prototype, "testObject", DefOptOfObjectRef("ObjB", "notExist")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = factory.GetObject("testObjName"); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if _, err = factory.GetObject("badObj"); err == nil {
+		t.Error("bad object should fail")
+		return
+	}
+
+	counters := []struct {
+		name       string
+		definition string
+		value      uint64
+	}{
+		{MetricInstancesCreated, "testObjName", 3},
+		{MetricInstancesCreated, "testObjBName", 1},
+		{MetricSingletonCacheMisses, "testObjBName", 1},
+		{MetricSingletonCacheHits, "testObjBName", 2},
+		{MetricCreationFailures, "badObj", 1},
+	}
+
+	for _, c := range counters {
+		if v := sink.Counter(c.name, c.definition); v != c.value {
+			t.Errorf("%s of %s should be %d, got: %d", c.name, c.definition, c.value, v)
+			return
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err = sink.WriteTo(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	text := buf.String()
+
+	for _, line := range []string{
+		"# TYPE factory_instances_created_total counter",
+		`factory_instances_created_total{definition="testObjName"} 3`,
+		"# TYPE factory_constructor_duration_seconds histogram",
+		`factory_constructor_duration_seconds_bucket{definition="testObjName",le="+Inf"} 3`,
+		`factory_injection_duration_seconds_count{definition="testObjName"} 3`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("exposition should contain %q, got:\n%s", line, text)
+			return
+		}
+	}
+}
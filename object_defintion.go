@@ -1,9 +1,11 @@
 package factory
 
 import (
+	"context"
 	"github.com/gogap/errors"
 	"reflect"
 	"strings"
+	"time"
 )
 
 type Scope int
@@ -11,10 +13,26 @@ type Scope int
 const (
 	Singleton Scope = 0
 	Prototype Scope = 1
+	Pooled    Scope = 2
 )
 
+func (p Scope) String() string {
+	switch p {
+	case Singleton:
+		return "singleton"
+	case Prototype:
+		return "prototype"
+	case Pooled:
+		return "pooled"
+	}
+
+	return "unknown"
+}
+
 type NewObjectFunc func(opts Options) (v interface{}, err error)
 
+type NewObjectFuncCtx func(ctx context.Context, opts Options) (v interface{}, err error)
+
 type DefinitionOption struct {
 	f func(o *ObjectDefinition) (err error)
 }
@@ -26,11 +44,50 @@ type ObjectDefinition struct {
 	scope Scope
 
 	newObjFunc      NewObjectFunc
+	newObjFuncCtx   NewObjectFuncCtx
+	creationTimeout time.Duration
 	typ             reflect.Type
 	refs            map[string]string
 	refsOptions     map[string]Options
+	refsLazy        map[string]reflect.Type
+	refsCollection  map[string]*collectionRef
+	refsTyped       map[string]*typedRef
+	refsOptional    map[string]bool
 	refsOrder       []string
 	initialFuncName string
+	destroyFuncName string
+
+	tags      []string
+	order     int
+	qualifier string
+	primary   bool
+	profiles  []string
+	lazyInit  bool
+	keyed     bool
+
+	poolConfig PoolConfig
+
+	optionsType    reflect.Type
+	defaultOptions Options
+	propagateKeys  []string
+
+	eventListeners []string
+
+	critical      bool
+	healthTimeout time.Duration
+
+	proxyType reflect.Type
+	advices   []advice
+}
+
+type collectionRef struct {
+	tag string
+	typ reflect.Type
+}
+
+type typedRef struct {
+	qualifier string
+	typ       reflect.Type
 }
 
 func (p *ObjectDefinition) Name() string {
@@ -57,10 +114,22 @@ func (p *ObjectDefinition) NewObjectFunc() NewObjectFunc {
 	return p.newObjFunc
 }
 
+func (p *ObjectDefinition) NewObjectFuncCtx() NewObjectFuncCtx {
+	return p.newObjFuncCtx
+}
+
+func (p *ObjectDefinition) CreationTimeout() time.Duration {
+	return p.creationTimeout
+}
+
 func (p *ObjectDefinition) InitialFuncName() string {
 	return p.initialFuncName
 }
 
+func (p *ObjectDefinition) DestroyFuncName() string {
+	return p.destroyFuncName
+}
+
 func (p *ObjectDefinition) Aliases() []string {
 	return p.aliases
 }
@@ -69,6 +138,144 @@ func (p *ObjectDefinition) Type() reflect.Type {
 	return p.typ
 }
 
+func (p *ObjectDefinition) Tags() []string {
+	return p.tags
+}
+
+func (p *ObjectDefinition) Order() int {
+	return p.order
+}
+
+func (p *ObjectDefinition) Qualifier() string {
+	return p.qualifier
+}
+
+func (p *ObjectDefinition) IsPrimary() bool {
+	return p.primary
+}
+
+func (p *ObjectDefinition) Profiles() []string {
+	return p.profiles
+}
+
+func (p *ObjectDefinition) IsKeyedSingleton() bool {
+	return p.keyed
+}
+
+func (p *ObjectDefinition) IsLazyInit() bool {
+	return p.lazyInit
+}
+
+// RefFields returns the ref field names in the initial order
+func (p *ObjectDefinition) RefFields() []string {
+	return removeDuplicatesInOrder(p.refsOrder)
+}
+
+func (p *ObjectDefinition) RefDefinitionName(fieldName string) string {
+	return p.refs[fieldName]
+}
+
+func (p *ObjectDefinition) RefOptions(fieldName string) Options {
+	return p.refsOptions[fieldName]
+}
+
+// Apply rewrite the definition with options, it should only be called
+// by DefinitionPostProcessor
+func (p *ObjectDefinition) Apply(opts ...DefinitionOption) error {
+	return p.options(opts...)
+}
+
+func (p *ObjectDefinition) DefaultOptions() Options {
+	return p.defaultOptions
+}
+
+func (p *ObjectDefinition) PropagateKeys() []string {
+	return p.propagateKeys
+}
+
+func (p *ObjectDefinition) propagatedOptions(opts Options) (propagated Options) {
+	for _, key := range p.propagateKeys {
+		if v, exist := opts[key]; exist {
+			if propagated == nil {
+				propagated = make(Options)
+			}
+			propagated[key] = v
+		}
+	}
+
+	return
+}
+
+func (p *ObjectDefinition) HasTag(tag string) bool {
+	for _, t := range p.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *ObjectDefinition) isAssignableTo(typ reflect.Type) bool {
+	return reflect.PtrTo(p.typ).AssignableTo(typ)
+}
+
+func (p *ObjectDefinition) fieldType(fieldName string) (typ reflect.Type, err error) {
+
+	fieldNames := strings.Split(fieldName, ".")
+
+	typ = p.typ
+
+	for _, fn := range fieldNames {
+
+		fn = strings.TrimSpace(fn)
+		if fn == "" {
+			err = ErrBadFieldName.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		for {
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			} else {
+				break
+			}
+		}
+
+		if typ.Kind() != reflect.Struct {
+			err = ErrStructFieldNotExist.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		field, exist := typ.FieldByName(fn)
+		if !exist {
+			err = ErrStructFieldNotExist.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		typ = field.Type
+	}
+
+	return
+}
+
+func (p *ObjectDefinition) isRef(fieldName string) bool {
+	if _, exist := p.refs[fieldName]; exist {
+		return true
+	}
+
+	if _, exist := p.refsCollection[fieldName]; exist {
+		return true
+	}
+
+	_, exist := p.refsTyped[fieldName]
+	return exist
+}
+
+func (p *ObjectDefinition) refsCount() int {
+	return len(p.refs) + len(p.refsCollection) + len(p.refsTyped)
+}
+
 func (p *ObjectDefinition) options(opts ...DefinitionOption) (err error) {
 	if opts == nil {
 		return
@@ -89,6 +296,23 @@ func DefOptOfNewObjectFunc(fn NewObjectFunc) DefinitionOption {
 	}}
 }
 
+func DefOptOfNewObjectFuncCtx(fn NewObjectFuncCtx) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.newObjFuncCtx = fn
+		return
+	}}
+}
+
+// DefOptOfCreationTimeout limit the time of new object func of the definition, the
+// func keeps running after timeout if it ignores the ctx, the object it returns late
+// is destroyed by the destroy func
+func DefOptOfCreationTimeout(timeout time.Duration) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.creationTimeout = timeout
+		return
+	}}
+}
+
 func DefOptOfObjectRef(fieldName string, refDefName string, opts ...Options) DefinitionOption {
 	return DefinitionOption{func(od *ObjectDefinition) (err error) {
 
@@ -111,46 +335,116 @@ func DefOptOfObjectRef(fieldName string, refDefName string, opts ...Options) Def
 			}
 		}
 
-		fieldNames := strings.Split(fieldName, ".")
-		lenfields := len(fieldNames)
+		if _, exist := od.refs[fieldName]; !exist && od.isRef(fieldName) {
+			err = ErrFiledAreadyRef.New(errors.Params{"name": fieldName})
+			return
+		}
 
-		typ := od.typ
+		var typ reflect.Type
+		if typ, err = od.fieldType(fieldName); err != nil {
+			return
+		}
 
-		for i, fn := range fieldNames {
+		if !isRefFieldType(typ) {
+			err = ErrRefFieldShouldBePtr.New()
+			return
+		}
 
-			fn = strings.TrimSpace(fn)
-			if fn == "" {
-				err = ErrBadFieldName.New(errors.Params{"name": fieldName})
-				return
-			}
+		if isProviderType(typ) {
+			od.refsLazy[fieldName] = typ
+		}
 
-			var field reflect.StructField
-			var exist bool
+		od.refs[fieldName] = refDefName
+		if opts != nil && len(opts) > 0 {
+			od.refsOptions[fieldName] = opts[0]
+		}
 
-			for {
-				if typ.Kind() == reflect.Ptr {
-					typ = typ.Elem()
-				} else {
-					break
-				}
-			}
+		od.refsOrder = append(od.refsOrder, fieldName)
 
-			if field, exist = typ.FieldByName(fn); !exist {
-				err = ErrStructFieldNotExist.New(errors.Params{"name": fieldName})
-				return
-			}
+		return
+	}}
+}
 
-			if i+1 >= lenfields {
-				if field.Type.Kind() != reflect.Ptr {
-					err = ErrRefFieldShouldBePtr.New()
-					return
-				}
-			}
+// DefOptOfCollectionRef inject all definitions which match the element type
+// of a []T or map[string]T field, if tag is not empty, only the definitions
+// with the tag or qualifier will be injected, the map key is the definition name
+func DefOptOfCollectionRef(fieldName string, tag string, opts ...Options) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+
+		fieldName = strings.TrimSpace(fieldName)
 
-			typ = field.Type
+		if fieldName == "" {
+			err = ErrEmptyFieldName.New()
+			return
 		}
 
-		od.refs[fieldName] = refDefName
+		if od.isRef(fieldName) {
+			err = ErrFiledAreadyRef.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		var typ reflect.Type
+		if typ, err = od.fieldType(fieldName); err != nil {
+			return
+		}
+
+		if typ.Kind() != reflect.Slice &&
+			!(typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String) {
+			err = ErrCollectionFieldTypeNotSupported.New(errors.Params{"name": fieldName, "type": typ.String()})
+			return
+		}
+
+		if typ.Elem().Kind() != reflect.Ptr && typ.Elem().Kind() != reflect.Interface {
+			err = ErrCollectionFieldTypeNotSupported.New(errors.Params{"name": fieldName, "type": typ.String()})
+			return
+		}
+
+		od.refsCollection[fieldName] = &collectionRef{tag: tag, typ: typ}
+		if opts != nil && len(opts) > 0 {
+			od.refsOptions[fieldName] = opts[0]
+		}
+
+		od.refsOrder = append(od.refsOrder, fieldName)
+
+		return
+	}}
+}
+
+// DefOptOfTypedRef inject the definition which match the field type,
+// if there are several candidates, the one with the qualifier or the primary one
+// will be selected
+func DefOptOfTypedRef(fieldName string, qualifier string, opts ...Options) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+
+		fieldName = strings.TrimSpace(fieldName)
+
+		if fieldName == "" {
+			err = ErrEmptyFieldName.New()
+			return
+		}
+
+		if od.isRef(fieldName) {
+			err = ErrFiledAreadyRef.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		var typ reflect.Type
+		if typ, err = od.fieldType(fieldName); err != nil {
+			return
+		}
+
+		if !isRefFieldType(typ) {
+			err = ErrRefFieldShouldBePtr.New()
+			return
+		}
+
+		refType := typ
+		if isProviderType(typ) {
+			od.refsLazy[fieldName] = typ
+			refType = typ.Out(0)
+		}
+
+		od.refsTyped[fieldName] = &typedRef{qualifier: qualifier, typ: refType}
 		if opts != nil && len(opts) > 0 {
 			od.refsOptions[fieldName] = opts[0]
 		}
@@ -161,6 +455,111 @@ func DefOptOfObjectRef(fieldName string, refDefName string, opts ...Options) Def
 	}}
 }
 
+func DefOptOfQualifier(qualifier string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.qualifier = qualifier
+		return
+	}}
+}
+
+// DefOptOfPrimary mark the definition as the preferred one when
+// several definitions match a typed ref
+func DefOptOfPrimary() DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.primary = true
+		return
+	}}
+}
+
+func DefOptOfTags(tags ...string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.tags = append(od.tags, tags...)
+		return
+	}}
+}
+
+// DefOptOfOrder set the order of definition in collection refs, the lower first
+func DefOptOfOrder(order int) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.order = order
+		return
+	}}
+}
+
+// DefOptOfOptionalRef is the same as DefOptOfObjectRef, but if the ref definition
+// does not exist or its profiles are not active, the field keeps its zero value
+func DefOptOfOptionalRef(fieldName string, refDefName string, opts ...Options) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		if err = DefOptOfObjectRef(fieldName, refDefName, opts...).f(od); err != nil {
+			return
+		}
+
+		od.refsOptional[strings.TrimSpace(fieldName)] = true
+		return
+	}}
+}
+
+// DefOptOfProfiles make the definition only active when one of the profiles is active
+func DefOptOfProfiles(profiles ...string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.profiles = append(od.profiles, profiles...)
+		return
+	}}
+}
+
+// DefOptOfDefaultOptions set the default options of the definition, the options
+// are merged as: default options < propagated options < ref options < call options
+func DefOptOfDefaultOptions(opts Options) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.defaultOptions = MergeOptions(od.defaultOptions, opts)
+		return
+	}}
+}
+
+// DefOptOfPropagateOptions pass the options of keys down to all refs
+func DefOptOfPropagateOptions(keys ...string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.propagateKeys = append(od.propagateKeys, keys...)
+		return
+	}}
+}
+
+func DefOptOfScope(scope Scope) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.scope = scope
+		return
+	}}
+}
+
+func DefOptOfRefOptions(fieldName string, opts Options) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		if !od.isRef(fieldName) {
+			err = ErrFieldIsNotRef.New(errors.Params{"name": fieldName})
+			return
+		}
+
+		od.refsOptions[fieldName] = opts
+		return
+	}}
+}
+
+// DefOptOfKeyedSingleton make the singleton cache one instance per effective options,
+// it will not be created by Start
+func DefOptOfKeyedSingleton() DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.keyed = true
+		return
+	}}
+}
+
+// DefOptOfLazyInit make the singleton not be created by Start
+func DefOptOfLazyInit() DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.lazyInit = true
+		return
+	}}
+}
+
 func DefOptOfInitialFunc(fnName string) DefinitionOption {
 	return DefinitionOption{func(od *ObjectDefinition) (err error) {
 		od.initialFuncName = fnName
@@ -168,19 +567,28 @@ func DefOptOfInitialFunc(fnName string) DefinitionOption {
 	}}
 }
 
+// DefOptOfDestroyFunc set the method called when the cached instance is destroyed by
+// RemoveDefinition, Redefine or Refresh, the method should be func() or func() error
+func DefOptOfDestroyFunc(fnName string) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		od.destroyFuncName = fnName
+		return
+	}}
+}
+
 func DefOptOfRefOrder(check bool, order ...string) DefinitionOption {
 	return DefinitionOption{func(od *ObjectDefinition) (err error) {
 		if check {
 
 			tmpOrder := removeDuplicates(order)
 
-			if len(tmpOrder) != len(od.refs) {
+			if len(tmpOrder) != od.refsCount() {
 				err = ErrBadRefOrderLength.New()
 				return
 			}
 
 			for _, filedName := range tmpOrder {
-				if _, exist := od.refs[filedName]; !exist {
+				if !od.isRef(filedName) {
 					err = ErrRefOrderContainNonExistRef.New(errors.Params{"name": filedName})
 					return
 				}
@@ -192,6 +600,10 @@ func DefOptOfRefOrder(check bool, order ...string) DefinitionOption {
 	}}
 }
 
+func isRefFieldType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface || isProviderType(typ)
+}
+
 func removeDuplicates(elements []string) []string {
 	encountered := map[string]bool{}
 
@@ -205,3 +617,16 @@ func removeDuplicates(elements []string) []string {
 	}
 	return result
 }
+
+func removeDuplicatesInOrder(elements []string) (result []string) {
+	encountered := map[string]bool{}
+
+	for _, e := range elements {
+		if !encountered[e] {
+			encountered[e] = true
+			result = append(result, e)
+		}
+	}
+
+	return
+}
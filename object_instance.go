@@ -6,9 +6,17 @@ import (
 
 type ObjectInstance struct {
 	id         string
+	key        string
 	object     interface{}
 	options    Options
 	definition *ObjectDefinition
+
+	// requested is the options passed by the caller, before the default options
+	// merged and the placeholders resolved, the rebuilt singleton is created by it
+	requested Options
+
+	// ready is set after the instance initialized
+	ready bool
 }
 
 func (p *ObjectInstance) String() string {
@@ -19,6 +27,20 @@ func (p *ObjectInstance) Id() string {
 	return p.id
 }
 
+// Key is the hash of options of keyed singleton, it is empty for others
+func (p *ObjectInstance) Key() string {
+	return p.key
+}
+
+// instanceKey is the key of instance cached in the factory
+func (p *ObjectInstance) instanceKey() string {
+	if p.key != "" {
+		return keyedInstanceKey(p.definition.Name(), p.key)
+	}
+
+	return p.definition.Name()
+}
+
 func (p *ObjectInstance) Instance() interface{} {
 	return p.object
 }
@@ -0,0 +1,307 @@
+package factory
+
+import (
+	"context"
+	"github.com/gogap/errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type PoolConfig struct {
+	MinSize     int
+	MaxSize     int
+	IdleTimeout time.Duration
+	// Validate is called on borrow, the invalid idle object will be discarded
+	Validate func(obj interface{}) bool
+}
+
+// DefOptOfPool set the pool config of the Pooled scope, MaxSize should be greater than 0
+func DefOptOfPool(config PoolConfig) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		if config.MaxSize <= 0 || config.MinSize < 0 || config.MinSize > config.MaxSize {
+			err = ErrBadPoolConfig.New(errors.Params{"name": od.Name(), "min": config.MinSize, "max": config.MaxSize})
+			return
+		}
+
+		od.poolConfig = config
+		return
+	}}
+}
+
+func (p *ObjectDefinition) PoolConfig() PoolConfig {
+	return p.poolConfig
+}
+
+type pooledObject struct {
+	object   interface{}
+	idleTime time.Time
+}
+
+type objectPool struct {
+	def     *ObjectDefinition
+	create  func(ctx context.Context) (interface{}, error)
+	destroy func(obj interface{})
+
+	// tokens limit the borrowed objects, objects are only created when no idle
+	// object, so the count of idle and borrowed objects never exceeds MaxSize
+	tokens chan struct{}
+
+	locker sync.Mutex
+	idle   []*pooledObject
+}
+
+func newObjectPool(ctx context.Context, def *ObjectDefinition, create func(ctx context.Context) (interface{}, error), destroy func(obj interface{})) (pool *objectPool, err error) {
+
+	config := def.PoolConfig()
+	if config.MaxSize <= 0 {
+		err = ErrBadPoolConfig.New(errors.Params{"name": def.Name(), "min": config.MinSize, "max": config.MaxSize})
+		return
+	}
+
+	pool = &objectPool{
+		def:     def,
+		create:  create,
+		destroy: destroy,
+		tokens:  make(chan struct{}, config.MaxSize),
+	}
+
+	for i := 0; i < config.MinSize; i++ {
+		var obj interface{}
+		if obj, err = create(ctx); err != nil {
+			for _, item := range pool.idle {
+				pool.destroyAll([]interface{}{item.object})
+			}
+			pool = nil
+			return
+		}
+
+		pool.idle = append(pool.idle, &pooledObject{object: obj, idleTime: time.Now()})
+	}
+
+	return
+}
+
+func (p *objectPool) borrow(ctx context.Context) (obj interface{}, err error) {
+
+	select {
+	case p.tokens <- struct{}{}:
+	case <-ctx.Done():
+		err = ErrPoolExhausted.New(errors.Params{"name": p.def.Name(), "err": ctx.Err()})
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			<-p.tokens
+		}
+	}()
+
+	validate := p.def.PoolConfig().Validate
+
+	for {
+		p.locker.Lock()
+		evicted := p.evictIdle()
+
+		var item *pooledObject
+		if n := len(p.idle); n > 0 {
+			item = p.idle[n-1]
+			p.idle = p.idle[:n-1]
+		}
+		p.locker.Unlock()
+
+		p.destroyAll(evicted)
+
+		if item == nil {
+			break
+		}
+
+		if validate == nil || validate(item.object) {
+			obj = item.object
+			return
+		}
+
+		p.destroyAll([]interface{}{item.object})
+	}
+
+	obj, err = p.create(ctx)
+
+	return
+}
+
+func (p *objectPool) giveBack(obj interface{}) {
+	p.locker.Lock()
+	p.idle = append(p.idle, &pooledObject{object: obj, idleTime: time.Now()})
+	p.locker.Unlock()
+
+	<-p.tokens
+
+	p.locker.Lock()
+	evicted := p.evictIdle()
+	p.locker.Unlock()
+
+	p.destroyAll(evicted)
+}
+
+// discard destroy the borrowed object rather than give it back
+func (p *objectPool) discard(obj interface{}) {
+	<-p.tokens
+
+	if obj != nil {
+		p.destroyAll([]interface{}{obj})
+	}
+}
+
+// evictIdle remove the objects idle longer than IdleTimeout, but keep MinSize objects,
+// the idle objects are in the order of idle time, the evicted should be destroyed
+// after the locker released
+func (p *objectPool) evictIdle() (evicted []interface{}) {
+	config := p.def.PoolConfig()
+	if config.IdleTimeout <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-config.IdleTimeout)
+
+	live := len(p.idle) + len(p.tokens)
+
+	var kept []*pooledObject
+	for _, item := range p.idle {
+		if item.idleTime.Before(deadline) && live > config.MinSize {
+			live--
+			evicted = append(evicted, item.object)
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	p.idle = kept
+
+	return
+}
+
+func (p *objectPool) destroyAll(objs []interface{}) {
+	if p.destroy == nil {
+		return
+	}
+
+	for _, obj := range objs {
+		p.destroy(obj)
+	}
+}
+
+func (p *objectPool) idleCount() int {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	return len(p.idle)
+}
+
+func (p *ClassicFactory) getObjectPool(ctx context.Context, def *ObjectDefinition) (pool *objectPool, err error) {
+	p.poolLocker.Lock()
+	pool, exist := p.objPools[def.Name()]
+	p.poolLocker.Unlock()
+
+	if exist {
+		return
+	}
+
+	create := func(ctx context.Context) (obj interface{}, err error) {
+		ctx = withResolutionStep(ctx, "", def.Name())
+		obj, err = p.resolveObject(ctx, def, nil)
+		err = newResolutionError(ctx, err)
+		return
+	}
+
+	// the objects discarded by the pool are destroyed as the instances of def
+	destroy := func(obj interface{}) {
+		p.destroy(&detachedInstances{instances: []*ObjectInstance{{object: obj, definition: def}}})
+	}
+
+	// the MinSize objects are created outside the locker, so the other pools are not blocked
+	var newPool *objectPool
+	if newPool, err = newObjectPool(ctx, def, create, destroy); err != nil {
+		return
+	}
+
+	p.poolLocker.Lock()
+	if pool, exist = p.objPools[def.Name()]; !exist {
+		pool = newPool
+		p.objPools[def.Name()] = pool
+	}
+	p.poolLocker.Unlock()
+
+	// the pool is created concurrently, the objects of new one are destroyed
+	if pool != newPool {
+		for _, item := range newPool.idle {
+			newPool.destroyAll([]interface{}{item.object})
+		}
+	}
+
+	return
+}
+
+// Borrow get an object from the pool of Pooled definition, it blocks until
+// an object returned if the pool is exhausted, or the ctx done
+func (p *ClassicFactory) Borrow(ctx context.Context, name string) (obj interface{}, err error) {
+
+	if err = p.prepare(); err != nil {
+		return
+	}
+
+	var def *ObjectDefinition
+	if def, err = p.getObjDefinition(name); err != nil {
+		return
+	}
+
+	if def.Scope() != Pooled {
+		err = ErrObjectIsNotPooled.New(errors.Params{"name": name})
+		return
+	}
+
+	var pool *objectPool
+	if pool, err = p.getObjectPool(ctx, def); err != nil {
+		return
+	}
+
+	if obj, err = pool.borrow(ctx); err != nil {
+		p.publish(Event{Type: ResolutionFailed, Definition: def, Err: err})
+		return
+	}
+
+	if obj == nil || !reflect.TypeOf(obj).Comparable() {
+		pool.discard(obj)
+		obj = nil
+		err = ErrPooledObjectNotComparable.New(errors.Params{"name": name})
+		return
+	}
+
+	p.poolLocker.Lock()
+	p.borrowedObj[obj] = pool
+	p.poolLocker.Unlock()
+
+	return
+}
+
+// Return give the borrowed object back to its pool
+func (p *ClassicFactory) Return(obj interface{}) (err error) {
+
+	if obj == nil || !reflect.TypeOf(obj).Comparable() {
+		err = ErrObjectNotBorrowed.New()
+		return
+	}
+
+	p.poolLocker.Lock()
+	pool, exist := p.borrowedObj[obj]
+	delete(p.borrowedObj, obj)
+	p.poolLocker.Unlock()
+
+	if !exist {
+		err = ErrObjectNotBorrowed.New()
+		return
+	}
+
+	pool.giveBack(obj)
+
+	return
+}
@@ -3,23 +3,101 @@ package factory
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/gogap/errors"
 	"reflect"
+	"strings"
+	"time"
 )
 
 type Options map[string]interface{}
 
-func (p Options) Get(name string, v interface{}) (exist bool) {
+// Get set the option of name to v, v should be a non-nil ptr, the option
+// will be converted to the type of v, name could be a nested path like db.pool.size
+func (p Options) Get(name string, v interface{}) (exist bool, err error) {
+
+	valVal := reflect.ValueOf(v)
+	if valVal.Kind() != reflect.Ptr || valVal.IsNil() {
+		err = ErrOptionTargetShouldBePtr.New(errors.Params{"name": name})
+		return
+	}
 
 	var opt interface{}
-	if opt, exist = p[name]; exist {
-		valOpt := reflect.ValueOf(opt)
-		valVal := reflect.ValueOf(v)
+	if opt, exist = p.lookup(name); !exist {
+		return
+	}
+
+	valVal = valVal.Elem()
+
+	var valOpt reflect.Value
+	if valOpt, err = convertValue(opt, valVal.Type()); err != nil {
+		err = ErrBadOptionValue.New(errors.Params{"name": name, "err": err})
+		return
+	}
+
+	valVal.Set(valOpt)
+
+	return
+}
+
+func (p Options) GetString(name string, defaultValue ...string) (v string, err error) {
+	if len(defaultValue) > 0 {
+		v = defaultValue[0]
+	}
 
-		if valVal.Kind() == reflect.Ptr {
-			valVal = valVal.Elem()
+	_, err = p.Get(name, &v)
+	return
+}
+
+func (p Options) GetInt(name string, defaultValue ...int) (v int, err error) {
+	if len(defaultValue) > 0 {
+		v = defaultValue[0]
+	}
+
+	_, err = p.Get(name, &v)
+	return
+}
+
+func (p Options) GetDuration(name string, defaultValue ...time.Duration) (v time.Duration, err error) {
+	if len(defaultValue) > 0 {
+		v = defaultValue[0]
+	}
+
+	_, err = p.Get(name, &v)
+	return
+}
+
+func (p Options) GetOptions(name string) (v Options, err error) {
+
+	opt, exist := p.lookup(name)
+	if !exist {
+		return
+	}
+
+	var ok bool
+	if v, ok = toOptions(opt); !ok {
+		err = ErrBadOptionValue.New(errors.Params{"name": name, "err": "value is not an object"})
+		return
+	}
+
+	return
+}
+
+func (p Options) lookup(name string) (v interface{}, exist bool) {
+	if v, exist = p[name]; exist {
+		return
+	}
+
+	v = p
+	for _, key := range strings.Split(name, ".") {
+		var opts Options
+		if opts, exist = toOptions(v); !exist {
+			v = nil
+			return
 		}
 
-		valVal.Set(valOpt)
+		if v, exist = opts[key]; !exist {
+			return
+		}
 	}
 
 	return
@@ -41,3 +119,42 @@ func (p Options) ToObject(v interface{}) (err error) {
 
 	return
 }
+
+// MergeOptions deep merge the options, the latter overrides the former,
+// the nested maps are merged rather than replaced
+func MergeOptions(opts ...Options) (merged Options) {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if merged == nil {
+			merged = make(Options, len(opt))
+		}
+
+		for k, v := range opt {
+			base, baseIsMap := toOptions(merged[k])
+			override, overrideIsMap := toOptions(v)
+
+			if baseIsMap && overrideIsMap {
+				merged[k] = MergeOptions(base, override)
+				continue
+			}
+
+			merged[k] = v
+		}
+	}
+
+	return
+}
+
+func toOptions(v interface{}) (opts Options, ok bool) {
+	switch val := v.(type) {
+	case Options:
+		return val, true
+	case map[string]interface{}:
+		return Options(val), true
+	}
+
+	return
+}
@@ -0,0 +1,210 @@
+package factory
+
+import (
+	"fmt"
+	"github.com/gogap/errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefOptOfOptionsType declare the options struct of the definition, the options
+// will be decoded and validated by the struct before the object created,
+// the key of field is the name of json tag or the field name, the field supports
+// tags of `default:"value"` and `validate:"required,min=1,max=10,oneof=a b"`
+func DefOptOfOptionsType(v interface{}) DefinitionOption {
+	return DefinitionOption{func(od *ObjectDefinition) (err error) {
+		typ := reflect.TypeOf(v)
+
+		for typ != nil && typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		if typ == nil || typ.Kind() != reflect.Struct {
+			err = ErrOptionsTypeMustBeStruct.New(errors.Params{"name": od.Name()})
+			return
+		}
+
+		od.optionsType = typ
+		return
+	}}
+}
+
+func (p *ObjectDefinition) OptionsType() reflect.Type {
+	return p.optionsType
+}
+
+// decodeOptions returns a copy of opts, the values of the declared keys are
+// converted to the field types and the defaults are filled
+func decodeOptions(def *ObjectDefinition, opts Options) (decoded Options, err error) {
+	if def.OptionsType() == nil {
+		decoded = opts
+		return
+	}
+
+	return decodeOptionsOfType(def, "", def.OptionsType(), opts)
+}
+
+func decodeOptionsOfType(def *ObjectDefinition, prefix string, typ reflect.Type, opts Options) (decoded Options, err error) {
+
+	decoded = make(Options, len(opts))
+	for k, v := range opts {
+		decoded[k] = v
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := optionKeyOfField(field)
+		if key == "-" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		invalid := func(reason string) error {
+			return ErrInvalidOption.New(errors.Params{"name": def.Name(), "key": path, "reason": reason})
+		}
+
+		value, exist := opts[key]
+
+		if isOptionsStructType(field.Type) {
+			nested := Options{}
+			if exist {
+				switch v := value.(type) {
+				case Options:
+					nested = v
+				case map[string]interface{}:
+					nested = Options(v)
+				default:
+					err = invalid(fmt.Sprintf("should be an object of %s", field.Type))
+					return
+				}
+			}
+
+			if nested, err = decodeOptionsOfType(def, path, field.Type, nested); err != nil {
+				return
+			}
+
+			if exist || len(nested) > 0 {
+				decoded[key] = nested
+			}
+			continue
+		}
+
+		if !exist {
+			if defaultValue, hasDefault := field.Tag.Lookup("default"); hasDefault {
+				value, exist = defaultValue, true
+			}
+		}
+
+		var fieldVal reflect.Value
+
+		if exist {
+			if fieldVal, err = convertValue(value, field.Type); err != nil {
+				err = invalid(err.Error())
+				return
+			}
+
+			decoded[key] = fieldVal.Interface()
+		} else {
+			fieldVal = reflect.Zero(field.Type)
+		}
+
+		if err = validateOption(fieldVal, exist, field.Tag.Get("validate"), invalid); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func validateOption(val reflect.Value, exist bool, rules string, invalid func(string) error) (err error) {
+
+	if rules == "" {
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "":
+		case "required":
+			if !exist || val.IsZero() {
+				return invalid("required")
+			}
+		case "min", "max":
+			if !exist {
+				continue
+			}
+
+			var limit float64
+			if limit, err = strconv.ParseFloat(arg, 64); err != nil {
+				return invalid(fmt.Sprintf("bad rule %s", rule))
+			}
+
+			var size float64
+			switch val.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				size = float64(val.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				size = float64(val.Uint())
+			case reflect.Float32, reflect.Float64:
+				size = val.Float()
+			case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+				size = float64(val.Len())
+			default:
+				return invalid(fmt.Sprintf("rule %s not support type %s", rule, val.Type()))
+			}
+
+			if name == "min" && size < limit {
+				return invalid(fmt.Sprintf("should not be less than %s", arg))
+			}
+
+			if name == "max" && size > limit {
+				return invalid(fmt.Sprintf("should not be greater than %s", arg))
+			}
+		case "oneof":
+			if !exist {
+				continue
+			}
+
+			matched := false
+			for _, candidate := range strings.Fields(arg) {
+				if fmt.Sprint(val.Interface()) == candidate {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return invalid(fmt.Sprintf("should be one of [%s]", arg))
+			}
+		default:
+			return invalid(fmt.Sprintf("unknown rule %s", name))
+		}
+	}
+
+	return
+}
+
+func optionKeyOfField(field reflect.StructField) string {
+	if tag, exist := field.Tag.Lookup("json"); exist {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func isOptionsStructType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ.PkgPath() != "time"
+}
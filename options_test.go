@@ -0,0 +1,221 @@
+package factory
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testHubOptions struct {
+	ID      string        `json:"id" validate:"required"`
+	Size    int           `json:"size" default:"4" validate:"min=1,max=8"`
+	Color   string        `json:"color" default:"black" validate:"oneof=black white"`
+	Timeout time.Duration `json:"timeout" default:"1s"`
+	Pool    struct {
+		Max int `json:"max" default:"2"`
+	} `json:"pool"`
+}
+
+func TestClassicFactoryOfOptionsType(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var received Options
+	newB := func(opts Options) (v interface{}, err error) {
+		received = opts
+		return &testObjectB{}, nil
+	}
+
+	if err = factory.Define("hub", Prototype, "testObjectB",
+		DefOptOfNewObjectFunc(newB),
+		DefOptOfOptionsType(testHubOptions{})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("hub", Options{"id": "HUB01", "size": float64(6)}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	hubOpts := testHubOptions{}
+	if err = received.ToObject(&hubOpts); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if received["size"] != 6 || received["timeout"] != time.Second ||
+		hubOpts.ID != "HUB01" || hubOpts.Color != "black" || hubOpts.Pool.Max != 2 {
+		t.Errorf("options not decoded with defaults: %v", received)
+		return
+	}
+
+	checks := []struct {
+		opts Options
+		key  string
+	}{
+		{Options{}, "id"},
+		{Options{"id": "HUB01", "size": 9}, "size"},
+		{Options{"id": "HUB01", "size": "four"}, "size"},
+		{Options{"id": "HUB01", "color": "red"}, "color"},
+		{Options{"id": "HUB01", "pool": Options{"max": "many"}}, "pool.max"},
+	}
+
+	for _, check := range checks {
+		_, err = factory.GetObject("hub", check.opts)
+
+		if !ErrInvalidOption.IsEqual(err) {
+			t.Errorf("options %v should be invalid, got: %v", check.opts, err)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), "key: "+check.key+",") || !strings.Contains(err.Error(), "name: hub") {
+			t.Errorf("error should name the key %s and the definition, got: %s", check.key, err)
+		}
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	merged := MergeOptions(
+		Options{"a": 1, "db": Options{"host": "localhost", "pool": map[string]interface{}{"size": 1, "idle": 1}}},
+		nil,
+		Options{"b": 2, "db": map[string]interface{}{"pool": Options{"size": 10}}},
+	)
+
+	pool, _ := toOptions(merged["db"].(Options)["pool"])
+
+	if merged["a"] != 1 || merged["b"] != 2 || merged["db"].(Options)["host"] != "localhost" || pool["size"] != 10 || pool["idle"] != 1 {
+		t.Errorf("bad merged options: %v", merged)
+		return
+	}
+
+	if MergeOptions(nil, nil) != nil {
+		t.Error("merge of nil options should be nil")
+		return
+	}
+}
+
+func TestClassicFactoryOfDefaultOptions(t *testing.T) {
+
+	var err error
+
+	factory := NewClassicFactory(nil)
+
+	var received []Options
+	newB := func(opts Options) (v interface{}, err error) {
+		received = append(received, opts)
+		return &testObjectB{}, nil
+	}
+
+	if err = factory.Define("testObjBName", Prototype, "testObjectB",
+		DefOptOfNewObjectFunc(newB),
+		DefOptOfDefaultOptions(Options{"id": "default", "owner": "nobody", "size": 1})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject",
+		DefOptOfPropagateOptions("owner", "size"),
+		DefOptOfDefaultOptions(Options{"size": 2}),
+		DefOptOfObjectRef("ObjB", "testObjBName", Options{"id": "ref", "size": 3})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("testObjName", Options{"owner": "GoGap"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("testObjBName", Options{"size": 4}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	refOpts, callOpts := received[0], received[1]
+
+	if refOpts["id"] != "ref" || refOpts["owner"] != "GoGap" || refOpts["size"] != 3 {
+		t.Errorf("bad ref options: %v", refOpts)
+		return
+	}
+
+	if callOpts["id"] != "default" || callOpts["owner"] != "nobody" || callOpts["size"] != 4 {
+		t.Errorf("bad call options: %v", callOpts)
+		return
+	}
+}
+
+func TestOptionsGet(t *testing.T) {
+
+	var err error
+
+	opts := Options{
+		"count":   float64(3),
+		"port":    "8080",
+		"timeout": "1500ms",
+		"ratio":   1,
+		"db":      map[string]interface{}{"pool": Options{"size": json.Number("5")}},
+		"a.b":     "exact",
+	}
+
+	var count int
+	var exist bool
+	if exist, err = opts.Get("count", &count); err != nil || !exist || count != 3 {
+		t.Errorf("float64 option should be converted to int, got: %v, %v", count, err)
+		return
+	}
+
+	var ratio float64
+	if _, err = opts.Get("ratio", &ratio); err != nil || ratio != 1 {
+		t.Errorf("int option should be converted to float64, got: %v, %v", ratio, err)
+		return
+	}
+
+	var port int
+	if port, err = opts.GetInt("port"); err != nil || port != 8080 {
+		t.Errorf("string option should be converted to int, got: %v, %v", port, err)
+		return
+	}
+
+	var timeout time.Duration
+	if timeout, err = opts.GetDuration("timeout"); err != nil || timeout != 1500*time.Millisecond {
+		t.Errorf("bad duration option, got: %v, %v", timeout, err)
+		return
+	}
+
+	var size int
+	if size, err = opts.GetInt("db.pool.size"); err != nil || size != 5 {
+		t.Errorf("bad nested option, got: %v, %v", size, err)
+		return
+	}
+
+	var s string
+	if s, err = opts.GetString("a.b"); err != nil || s != "exact" {
+		t.Errorf("exact key should be preferred, got: %v, %v", s, err)
+		return
+	}
+
+	if s, err = opts.GetString("not.exist", "default"); err != nil || s != "default" {
+		t.Errorf("missing option should return the default, got: %v, %v", s, err)
+		return
+	}
+
+	var pool Options
+	if pool, err = opts.GetOptions("db.pool"); err != nil || pool["size"] == nil {
+		t.Errorf("bad options option, got: %v, %v", pool, err)
+		return
+	}
+
+	if _, err = opts.GetInt("timeout"); !ErrBadOptionValue.IsEqual(err) {
+		t.Errorf("bad int option should return error, got: %v", err)
+		return
+	}
+
+	if _, err = opts.Get("count", count); !ErrOptionTargetShouldBePtr.IsEqual(err) {
+		t.Errorf("non-ptr target should return error, got: %v", err)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogap/factory"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer adapt the OpenTelemetry tracer to factory.Tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+func (p *Tracer) Start(ctx context.Context, spanName string) (context.Context, factory.Span) {
+	ctx, span := p.tracer.Start(ctx, spanName)
+	return ctx, &Span{span: span}
+}
+
+type Span struct {
+	span trace.Span
+}
+
+func (p *Span) SetAttributes(attrs ...factory.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(attr.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(attr.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(attr.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(attr.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(attr.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+
+	p.span.SetAttributes(kvs...)
+}
+
+func (p *Span) RecordError(err error) {
+	p.span.RecordError(err)
+	p.span.SetStatus(codes.Error, err.Error())
+}
+
+func (p *Span) End() {
+	p.span.End()
+}
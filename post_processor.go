@@ -0,0 +1,87 @@
+package factory
+
+import (
+	"github.com/gogap/errors"
+	"reflect"
+)
+
+// ObjectPostProcessor is called around the initial func of every created object,
+// the returned object replaces the original one, so it could wrap the object
+type ObjectPostProcessor interface {
+	BeforeInit(obj interface{}, def *ObjectDefinition) (interface{}, error)
+	AfterInit(obj interface{}, def *ObjectDefinition) (interface{}, error)
+}
+
+func (p *ClassicFactory) RegisterPostProcessor(processor ObjectPostProcessor) {
+	p.objLocker.Lock()
+	defer p.objLocker.Unlock()
+
+	p.postProcessors = append(p.postProcessors, processor)
+}
+
+func (p *ClassicFactory) getPostProcessors() []ObjectPostProcessor {
+	p.objLocker.Lock()
+	defer p.objLocker.Unlock()
+
+	return p.postProcessors
+}
+
+func (p *ClassicFactory) initObject(def *ObjectDefinition, obj interface{}) (retObj interface{}, err error) {
+
+	// the proxy replaces the object after all the registered processors
+	processors := append(append([]ObjectPostProcessor{}, p.getPostProcessors()...), proxyPostProcessor{})
+
+	retObj = obj
+
+	for _, processor := range processors {
+		if retObj, err = processor.BeforeInit(retObj, def); err != nil {
+			return
+		}
+	}
+
+	if err = p.callInitialFunc(def, retObj); err != nil {
+		return
+	}
+
+	for _, processor := range processors {
+		if retObj, err = processor.AfterInit(retObj, def); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// callInitialFunc call the method of initial func name, the method should be
+// func() or func() error
+func (p *ClassicFactory) callInitialFunc(def *ObjectDefinition, obj interface{}) (err error) {
+	return callObjectFunc(def, obj, def.InitialFuncName(), ErrInitialFuncNotExist.New, ErrBadInitialFunc.New)
+}
+
+func callObjectFunc(def *ObjectDefinition, obj interface{}, fnName string, errNotExist, errBadFunc func(...errors.Params) errors.ErrCode) (err error) {
+
+	if fnName == "" {
+		return
+	}
+
+	fn := reflect.ValueOf(obj).MethodByName(fnName)
+	if !fn.IsValid() {
+		err = errNotExist(errors.Params{"name": def.Name(), "func": fnName})
+		return
+	}
+
+	fnType := fn.Type()
+	if fnType.NumIn() != 0 || fnType.NumOut() > 1 || (fnType.NumOut() == 1 && fnType.Out(0) != errorType) {
+		err = errBadFunc(errors.Params{"name": def.Name(), "func": fnName})
+		return
+	}
+
+	outs := fn.Call(nil)
+
+	if len(outs) == 1 && !outs[0].IsNil() {
+		err = outs[0].Interface().(error)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,262 @@
+package factory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/gogap/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type PropertySource interface {
+	Name() string
+	Property(key string) (value interface{}, exist bool)
+	Keys() []string
+}
+
+type MapPropertySource struct {
+	name       string
+	properties map[string]interface{}
+}
+
+// NewMapPropertySource flatten the nested maps of properties into dotted keys
+func NewMapPropertySource(name string, properties map[string]interface{}) *MapPropertySource {
+	flatten := make(map[string]interface{})
+	flattenProperties("", properties, flatten)
+
+	return &MapPropertySource{
+		name:       name,
+		properties: flatten,
+	}
+}
+
+func (p *MapPropertySource) Name() string {
+	return p.name
+}
+
+func (p *MapPropertySource) Property(key string) (value interface{}, exist bool) {
+	value, exist = p.properties[key]
+	return
+}
+
+func (p *MapPropertySource) Keys() []string {
+	return sortedKeys(p.properties)
+}
+
+// EnvPropertySource map the key of db.pool-size to env of PREFIX_DB_POOL_SIZE
+type EnvPropertySource struct {
+	prefix string
+}
+
+func NewEnvPropertySource(prefix string) *EnvPropertySource {
+	return &EnvPropertySource{prefix: prefix}
+}
+
+func (p *EnvPropertySource) Name() string {
+	return "env"
+}
+
+func (p *EnvPropertySource) envName(key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if p.prefix != "" {
+		name = strings.ToUpper(p.prefix) + "_" + name
+	}
+	return name
+}
+
+func (p *EnvPropertySource) Property(key string) (value interface{}, exist bool) {
+	return os.LookupEnv(p.envName(key))
+}
+
+func (p *EnvPropertySource) Keys() (keys []string) {
+	prefix := ""
+	if p.prefix != "" {
+		prefix = strings.ToUpper(p.prefix) + "_"
+	}
+
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+
+		keys = append(keys, strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, prefix), "_", ".")))
+	}
+
+	sort.Strings(keys)
+
+	return
+}
+
+// FlagPropertySource only contains the flags which have been set
+type FlagPropertySource struct {
+	flagSet *flag.FlagSet
+}
+
+func NewFlagPropertySource(flagSet *flag.FlagSet) *FlagPropertySource {
+	if flagSet == nil {
+		flagSet = flag.CommandLine
+	}
+
+	return &FlagPropertySource{flagSet: flagSet}
+}
+
+func (p *FlagPropertySource) Name() string {
+	return "flag:" + p.flagSet.Name()
+}
+
+func (p *FlagPropertySource) Property(key string) (value interface{}, exist bool) {
+	p.flagSet.Visit(func(f *flag.Flag) {
+		if f.Name == key {
+			value, exist = f.Value.String(), true
+		}
+	})
+
+	return
+}
+
+func (p *FlagPropertySource) Keys() (keys []string) {
+	p.flagSet.Visit(func(f *flag.Flag) {
+		keys = append(keys, f.Name)
+	})
+
+	return
+}
+
+// FilePropertySource load properties from .json file or key=value lines file
+type FilePropertySource struct {
+	filename string
+
+	locker     sync.RWMutex
+	properties map[string]interface{}
+}
+
+func NewFilePropertySource(filename string) (source *FilePropertySource, err error) {
+	source = &FilePropertySource{filename: filename}
+
+	if err = source.Reload(); err != nil {
+		source = nil
+		return
+	}
+
+	return
+}
+
+func (p *FilePropertySource) Name() string {
+	return "file:" + p.filename
+}
+
+func (p *FilePropertySource) Filename() string {
+	return p.filename
+}
+
+func (p *FilePropertySource) Property(key string) (value interface{}, exist bool) {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	value, exist = p.properties[key]
+	return
+}
+
+func (p *FilePropertySource) Keys() []string {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	return sortedKeys(p.properties)
+}
+
+// Snapshot returns the current properties, which could be restored by Restore
+func (p *FilePropertySource) Snapshot() map[string]interface{} {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	return p.properties
+}
+
+// Restore replace the properties by the snapshot
+func (p *FilePropertySource) Restore(snapshot map[string]interface{}) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	p.properties = snapshot
+}
+
+// Reload read the file again, the properties are kept if failed
+func (p *FilePropertySource) Reload() (err error) {
+
+	var data []byte
+	if data, err = os.ReadFile(p.filename); err != nil {
+		return
+	}
+
+	properties := make(map[string]interface{})
+
+	if strings.ToLower(filepath.Ext(p.filename)) == ".json" {
+		var nested map[string]interface{}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		if err = decoder.Decode(&nested); err != nil {
+			err = ErrBadPropertyFile.New(errors.Params{"file": p.filename, "err": err})
+			return
+		}
+
+		flattenProperties("", nested, properties)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+
+			key, value, found := strings.Cut(text, "=")
+			if !found {
+				err = ErrBadPropertyFile.New(errors.Params{"file": p.filename, "err": fmt.Sprintf("line %d should be key=value", line)})
+				return
+			}
+
+			properties[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	p.locker.Lock()
+	p.properties = properties
+	p.locker.Unlock()
+
+	return
+}
+
+func flattenProperties(prefix string, nested map[string]interface{}, flatten map[string]interface{}) {
+	for key, value := range nested {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenProperties(key, v, flatten)
+		case Options:
+			flattenProperties(key, v, flatten)
+		default:
+			flatten[key] = value
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return
+}
@@ -0,0 +1,67 @@
+package factory
+
+import (
+	"context"
+	"github.com/gogap/errors"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Provider is a lazy ref, the ref definition is resolved on every call,
+// so the scope of the ref definition is respected
+type Provider[T any] func() (T, error)
+
+// isProviderType reports whether typ has the shape of func() (T, error)
+func isProviderType(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Func {
+		return false
+	}
+
+	if typ.NumIn() != 0 || typ.NumOut() != 2 || typ.IsVariadic() {
+		return false
+	}
+
+	return typ.Out(1) == errorType
+}
+
+// newProvider create the func of provider type, ctx only carries the resolution path,
+// it will not be canceled with the creation of the object which owns the provider
+func (p *ClassicFactory) newProvider(ctx context.Context, typ reflect.Type, resolve func() (*ObjectDefinition, error), refOpts Options, optional bool) reflect.Value {
+	outType := typ.Out(0)
+
+	ctx = context.WithoutCancel(ctx)
+
+	return reflect.MakeFunc(typ, func(_ []reflect.Value) []reflect.Value {
+
+		var obj interface{}
+		var err error
+
+		outVal := reflect.Zero(outType)
+		errVal := reflect.Zero(errorType)
+
+		var refDef *ObjectDefinition
+		if refDef, err = resolve(); err == nil {
+			obj, err = p.getObject(withResolutionStep(ctx, "", refDef.Name()), refDef, refOpts)
+		} else if optional && isMissingDefinitionError(err) {
+			return []reflect.Value{outVal, errVal}
+		} else {
+			err = newResolutionError(ctx, err)
+		}
+
+		if err == nil {
+			objVal := reflect.ValueOf(obj)
+			if objVal.IsValid() && objVal.Type().AssignableTo(outType) {
+				outVal = objVal
+			} else {
+				err = newResolutionError(ctx, ErrRefTypeNotMatch.New(errors.Params{"name": refDef.Name(), "type": outType.String()}))
+			}
+		}
+
+		if err != nil {
+			errVal = reflect.ValueOf(&err).Elem()
+		}
+
+		return []reflect.Value{outVal, errVal}
+	})
+}
@@ -0,0 +1,311 @@
+package reload
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gogap/errors"
+	"github.com/gogap/factory"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event is sent to the listeners after the files reloaded, Err is not nil if
+// the files could not be loaded or the changes are rolled back
+type Event struct {
+	Files   []string
+	Added   []string
+	Changed []string
+	Removed []string
+	Err     error
+}
+
+type Listener func(event Event)
+
+type Option struct {
+	f func(p *Reloader)
+}
+
+func OptOfDefinitionFiles(files ...string) Option {
+	return Option{func(p *Reloader) {
+		p.definitionFiles = append(p.definitionFiles, files...)
+	}}
+}
+
+// OptOfPropertySources watch the files of sources, the file definitions are
+// rebuilt after the sources reloaded, the properties are rolled back on error
+func OptOfPropertySources(sources ...*factory.FilePropertySource) Option {
+	return Option{func(p *Reloader) {
+		p.propertySources = append(p.propertySources, sources...)
+	}}
+}
+
+// OptOfDebounce merge the file events in the duration into one reload
+func OptOfDebounce(debounce time.Duration) Option {
+	return Option{func(p *Reloader) {
+		p.debounce = debounce
+	}}
+}
+
+// Reloader load the definitions from files into the factory, and apply the
+// differences atomically when the definition or property files changed
+type Reloader struct {
+	factory factory.Factory
+
+	definitionFiles []string
+	propertySources []*factory.FilePropertySource
+	debounce        time.Duration
+
+	locker    sync.Mutex
+	specs     []factory.DefinitionSpec
+	listeners []Listener
+}
+
+func NewReloader(f factory.Factory, opts ...Option) *Reloader {
+	reloader := &Reloader{
+		factory:  f,
+		debounce: 100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt.f(reloader)
+	}
+
+	return reloader
+}
+
+func (p *Reloader) AddListener(listener Listener) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	p.listeners = append(p.listeners, listener)
+}
+
+// Specs returns the definition specs applied
+func (p *Reloader) Specs() []factory.DefinitionSpec {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	return p.specs
+}
+
+// Load load all the files and apply the differences
+func (p *Reloader) Load() (event Event) {
+	files := append([]string{}, p.definitionFiles...)
+	for _, source := range p.propertySources {
+		files = append(files, source.Filename())
+	}
+
+	return p.reload(files)
+}
+
+func (p *Reloader) reload(files []string) (event Event) {
+	p.locker.Lock()
+	event = p.apply(files)
+	p.locker.Unlock()
+
+	p.notify(event)
+
+	return
+}
+
+func (p *Reloader) apply(files []string) (event Event) {
+
+	event.Files = files
+
+	snapshots := make(map[*factory.FilePropertySource]map[string]interface{})
+
+	defer func() {
+		if event.Err != nil {
+			for source, snapshot := range snapshots {
+				source.Restore(snapshot)
+			}
+		}
+	}()
+
+	propertiesChanged := false
+	for _, source := range p.propertySources {
+		if !contains(files, source.Filename()) {
+			continue
+		}
+
+		propertiesChanged = true
+		snapshots[source] = source.Snapshot()
+
+		if event.Err = source.Reload(); event.Err != nil {
+			return
+		}
+	}
+
+	var specs []factory.DefinitionSpec
+	names := make(map[string]string)
+
+	for _, file := range p.definitionFiles {
+		var fileSpecs []factory.DefinitionSpec
+		if fileSpecs, event.Err = factory.LoadDefinitionFile(file); event.Err != nil {
+			return
+		}
+
+		for _, spec := range fileSpecs {
+			if otherFile, exist := names[spec.Name]; exist {
+				event.Err = factory.ErrBadDefinitionFile.New(errors.Params{"file": file, "err": "definition " + spec.Name + " is also in " + otherFile})
+				return
+			}
+			names[spec.Name] = file
+		}
+
+		specs = append(specs, fileSpecs...)
+	}
+
+	event.Added, event.Changed, event.Removed = factory.DiffDefinitionSpecs(p.specs, specs)
+
+	if propertiesChanged {
+		// the placeholders of all definitions may be changed
+		event.Changed = nil
+		for _, spec := range specs {
+			if !contains(event.Added, spec.Name) {
+				event.Changed = append(event.Changed, spec.Name)
+			}
+		}
+	}
+
+	var changes []factory.DefinitionChange
+
+	for _, spec := range specs {
+		if !contains(event.Added, spec.Name) && !contains(event.Changed, spec.Name) {
+			continue
+		}
+
+		var change factory.DefinitionChange
+		if change, event.Err = spec.Change(); event.Err != nil {
+			return
+		}
+
+		changes = append(changes, change)
+	}
+
+	for _, name := range event.Removed {
+		changes = append(changes, factory.DefinitionChange{Name: name, Remove: true})
+	}
+
+	if len(changes) > 0 {
+		registry, ok := p.factory.(factory.DefinitionRegistry)
+		if !ok {
+			event.Err = factory.ErrFactoryNotSupported.New(errors.Params{"interface": "DefinitionRegistry"})
+			return
+		}
+
+		if event.Err = registry.ApplyDefinitions(changes...); event.Err != nil {
+			return
+		}
+	}
+
+	p.specs = specs
+
+	return
+}
+
+// Watch reload the files when they changed until the ctx done, the directories
+// of files are watched, so the files replaced by editors are also reloaded
+func (p *Reloader) Watch(ctx context.Context) (err error) {
+
+	var watcher *fsnotify.Watcher
+	if watcher, err = fsnotify.NewWatcher(); err != nil {
+		return
+	}
+	defer watcher.Close()
+
+	files := append([]string{}, p.definitionFiles...)
+	for _, source := range p.propertySources {
+		files = append(files, source.Filename())
+	}
+
+	watching := make(map[string]string)
+	dirs := make(map[string]bool)
+
+	for _, file := range files {
+		var absFile string
+		if absFile, err = filepath.Abs(file); err != nil {
+			return
+		}
+
+		watching[absFile] = file
+
+		dir := filepath.Dir(absFile)
+		if dirs[dir] {
+			continue
+		}
+
+		if err = watcher.Add(dir); err != nil {
+			return
+		}
+		dirs[dir] = true
+	}
+
+	var timer <-chan time.Time
+	pending := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+
+			absFile, absErr := filepath.Abs(event.Name)
+			if absErr != nil {
+				continue
+			}
+
+			if file, exist := watching[absFile]; exist {
+				pending[file] = true
+				timer = time.After(p.debounce)
+			}
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			p.notify(Event{Err: watchErr})
+		case <-timer:
+			timer = nil
+
+			var changed []string
+			for file := range pending {
+				changed = append(changed, file)
+			}
+			sort.Strings(changed)
+
+			pending = make(map[string]bool)
+
+			p.reload(changed)
+		}
+	}
+}
+
+func (p *Reloader) notify(event Event) {
+	p.locker.Lock()
+	listeners := p.listeners
+	p.locker.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,240 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"github.com/gogap/factory"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEngine struct {
+	Power int
+}
+
+type testCar struct {
+	Engine *testEngine
+}
+
+func init() {
+	factory.RegisterModel((*testEngine)(nil), "reloadTestEngine")
+	factory.RegisterModel((*testCar)(nil), "reloadTestCar")
+}
+
+// testEngineConstructor set the constructor of engines, which fails on negative power
+type testEngineConstructor struct{}
+
+func (testEngineConstructor) PostProcessDefinitions(defs []*factory.ObjectDefinition) (err error) {
+	for _, def := range defs {
+		if def.Type() != reflect.TypeOf(testEngine{}) {
+			continue
+		}
+
+		err = def.Apply(factory.DefOptOfNewObjectFunc(func(opts factory.Options) (interface{}, error) {
+			power, err := opts.GetInt("power")
+			if err == nil && power < 0 {
+				err = errors.New("negative power")
+			}
+			return &testEngine{Power: power}, err
+		}))
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func TestReloaderLoad(t *testing.T) {
+
+	var err error
+
+	filename := filepath.Join(t.TempDir(), "definitions.json")
+
+	writeFile := func(content string) {
+		if err = os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(`[
+		{"name": "engine", "model": "reloadTestEngine"},
+		{"name": "car", "model": "reloadTestCar", "refs": {"Engine": "engine"}}
+	]`)
+
+	f := factory.NewClassicFactory(nil)
+
+	var events []Event
+	reloader := NewReloader(f, OptOfDefinitionFiles(filename))
+	reloader.AddListener(func(event Event) {
+		events = append(events, event)
+	})
+
+	if event := reloader.Load(); event.Err != nil || len(event.Added) != 2 {
+		t.Errorf("the definitions should be added, got: %+v", event)
+		return
+	}
+
+	var car1 interface{}
+	if car1, err = f.GetObject("car"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	writeFile(`[
+		{"name": "engine", "model": "reloadTestEngine"},
+		{"name": "car", "model": "reloadTestCar", "refs": {"Engine": "turbo"}},
+		{"name": "turbo", "model": "reloadTestEngine"}
+	]`)
+
+	if event := reloader.Load(); event.Err != nil || len(event.Added) != 1 || len(event.Changed) != 1 {
+		t.Errorf("the definitions should be changed, got: %+v", event)
+		return
+	}
+
+	var car2 interface{}
+	if car2, err = f.GetObject("car"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var turbo interface{}
+	if turbo, err = f.GetObject("turbo"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if car2 == car1 || car2.(*testCar).Engine != turbo {
+		t.Error("the singleton should be rebuilt with the new refs")
+		return
+	}
+
+	writeFile(`[
+		{"name": "engine", "model": "reloadTestEngine"},
+		{"name": "car", "model": "reloadTestCar", "refs": {"Engine": "missing"}}
+	]`)
+
+	if event := reloader.Load(); event.Err == nil {
+		t.Error("the bad definitions should be rolled back")
+		return
+	}
+
+	var car3 interface{}
+	if car3, err = f.GetObject("car"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if car3 != car2 || !f.ContainsObject("turbo") {
+		t.Error("the definitions and instances should be rolled back")
+		return
+	}
+
+	if len(events) != 3 || len(reloader.Specs()) != 3 {
+		t.Errorf("the listener should receive all events, got: %d", len(events))
+		return
+	}
+}
+
+func TestReloaderRollbackAndWatch(t *testing.T) {
+
+	var err error
+
+	dir := t.TempDir()
+	definitionFile := filepath.Join(dir, "definitions.json")
+	propertyFile := filepath.Join(dir, "engine.properties")
+
+	writeFile := func(filename, content string) {
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(definitionFile, `[
+		{"name": "engine", "model": "reloadTestEngine", "options": {"power": "${engine.power}"}},
+		{"name": "car", "model": "reloadTestCar", "refs": {"Engine": "engine"}}
+	]`)
+	writeFile(propertyFile, "engine.power=1")
+
+	source, err := factory.NewFilePropertySource(propertyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := factory.NewClassicFactory(nil)
+	f.Environment().AddFirst(source)
+
+	if err = f.RegisterDefinitionPostProcessor(testEngineConstructor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloader := NewReloader(f,
+		OptOfDefinitionFiles(definitionFile),
+		OptOfPropertySources(source),
+		OptOfDebounce(20*time.Millisecond),
+	)
+
+	if event := reloader.Load(); event.Err != nil {
+		t.Fatal(event.Err)
+	}
+
+	car1, err := f.GetObject("car")
+	if err != nil || car1.(*testCar).Engine.Power != 1 {
+		t.Fatalf("the car should be created with the property, got: %v", err)
+	}
+
+	writeFile(propertyFile, "engine.power=-1")
+
+	if event := reloader.Load(); event.Err == nil {
+		t.Fatalf("the failed constructor should fail the reload, got: %+v", event)
+	}
+
+	if car, _ := f.GetObject("car"); car != car1 {
+		t.Fatal("the instances should be rolled back")
+	}
+
+	if power, _ := f.Environment().Property("engine.power"); power != "1" {
+		t.Fatalf("the properties should be rolled back, got: %v", power)
+	}
+
+	events := make(chan Event, 1)
+	reloader.AddListener(func(event Event) {
+		events <- event
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watched := make(chan error, 1)
+	go func() {
+		watched <- reloader.Watch(ctx)
+	}()
+
+	// wait the watcher added
+	time.Sleep(100 * time.Millisecond)
+
+	writeFile(propertyFile, "engine.power=2")
+
+	select {
+	case event := <-events:
+		if event.Err != nil || len(event.Files) != 1 || event.Files[0] != propertyFile {
+			t.Fatalf("the property file should be reloaded, got: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the changed file should be reloaded by watcher")
+	}
+
+	if car, _ := f.GetObject("car"); car == car1 || car.(*testCar).Engine.Power != 2 {
+		t.Fatal("the car should be rebuilt with the new property")
+	}
+
+	cancel()
+
+	if err = <-watched; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gogap/errors"
+)
+
+// ResolutionError wraps the failure of resolving an object with the definition
+// chain and field path, it is also an errors.ErrCode of the cause, so the
+// IsEqual of the error code templates, errors.Is and errors.As still work
+type ResolutionError struct {
+	errors.ErrCode
+
+	// Definition is the name of the last definition in the path
+	Definition string
+	Path       []ResolutionStep
+	Cause      error
+}
+
+func newResolutionError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*ResolutionError); ok {
+		return err
+	}
+
+	path := resolutionPathOf(ctx)
+
+	resolutionErr := &ResolutionError{
+		Path:  path,
+		Cause: err,
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Definition != "" {
+			resolutionErr.Definition = path[i].Definition
+			break
+		}
+	}
+
+	if errCode, ok := err.(errors.ErrCode); ok {
+		resolutionErr.ErrCode = errCode
+	} else {
+		resolutionErr.ErrCode = ErrCouldNotResolveObject.New(errors.Params{"name": resolutionErr.Definition, "err": err})
+	}
+
+	return resolutionErr
+}
+
+func (p *ResolutionError) Error() string {
+	return formatResolutionPath(p.Path) + ": " + p.Cause.Error()
+}
+
+func (p *ResolutionError) Unwrap() error {
+	return p.Cause
+}
+
+// Is reports whether the target is an errors.ErrCode of the same namespace and code
+func (p *ResolutionError) Is(target error) bool {
+	errCode, ok := target.(errors.ErrCode)
+	return ok && errCode.Namespace() == p.Namespace() && errCode.Code() == p.Code()
+}
+
+func (p *ResolutionError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Namespace  string           `json:"namespace"`
+		Code       uint64           `json:"code"`
+		Definition string           `json:"definition"`
+		Path       []ResolutionStep `json:"path"`
+		Message    string           `json:"message"`
+	}{
+		Namespace:  p.Namespace(),
+		Code:       p.Code(),
+		Definition: p.Definition,
+		Path:       p.Path,
+		Message:    p.Cause.Error(),
+	})
+}
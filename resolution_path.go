@@ -0,0 +1,59 @@
+package factory
+
+import (
+	"context"
+	"github.com/gogap/errors"
+	"strings"
+)
+
+type resolutionPathKey struct{}
+
+// ResolutionStep is a step of resolution, the object of definition is
+// resolved for the field of the object of previous step
+type ResolutionStep struct {
+	Field      string `json:"field,omitempty"`
+	Definition string `json:"definition,omitempty"`
+}
+
+func withResolutionStep(ctx context.Context, field, definition string) context.Context {
+	path := resolutionPathOf(ctx)
+
+	newPath := make([]ResolutionStep, len(path), len(path)+1)
+	copy(newPath, path)
+	newPath = append(newPath, ResolutionStep{Field: field, Definition: definition})
+
+	return context.WithValue(ctx, resolutionPathKey{}, newPath)
+}
+
+func resolutionPathOf(ctx context.Context) []ResolutionStep {
+	path, _ := ctx.Value(resolutionPathKey{}).([]ResolutionStep)
+	return path
+}
+
+// formatResolutionPath format the path like: mycar -> Wheel3 -> wheel -> Hub -> hub
+func formatResolutionPath(path []ResolutionStep) string {
+	var items []string
+	for _, step := range path {
+		if step.Field != "" {
+			items = append(items, step.Field)
+		}
+
+		if step.Definition != "" {
+			items = append(items, step.Definition)
+		}
+	}
+
+	return strings.Join(items, " -> ")
+}
+
+func contextError(ctx context.Context, def *ObjectDefinition) (err error) {
+	switch ctx.Err() {
+	case nil:
+	case context.DeadlineExceeded:
+		err = ErrObjectCreationTimeout.New(errors.Params{"name": def.Name()})
+	default:
+		err = ErrObjectCreationCanceled.New(errors.Params{"name": def.Name()})
+	}
+
+	return
+}
@@ -0,0 +1,75 @@
+package factory
+
+import (
+	"context"
+)
+
+const (
+	TraceAttrDefinition = "factory.definition"
+	TraceAttrScope      = "factory.scope"
+	TraceAttrModel      = "factory.model"
+	TraceAttrCached     = "factory.cached"
+)
+
+// Tracer starts a span for every object resolution, the spans of refs are
+// started with the ctx returned by the span of the owner
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func FactoryOptOfTracer(tracer Tracer) FactoryOption {
+	return FactoryOption{func(p *ClassicFactory) {
+		if tracer != nil {
+			p.tracer = tracer
+		}
+	}}
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(attrs ...Attribute) {}
+
+func (nopSpan) RecordError(err error) {}
+
+func (nopSpan) End() {}
+
+type spanKey struct{}
+
+// startSpan start the span of resolving the definition, the span is kept in ctx,
+// so the resolution could add attributes to it
+func (p *ClassicFactory) startSpan(ctx context.Context, def *ObjectDefinition) (context.Context, Span) {
+	ctx, span := p.tracer.Start(ctx, "factory.GetObject "+def.Name())
+
+	span.SetAttributes(
+		Attribute{TraceAttrDefinition, def.Name()},
+		Attribute{TraceAttrScope, def.Scope().String()},
+		Attribute{TraceAttrModel, def.Type().PkgPath() + "::" + def.Type().String()},
+	)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func spanOf(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+
+	return nopSpan{}
+}
@@ -0,0 +1,91 @@
+package factory
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (p *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		p.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (p *testSpan) RecordError(err error) { p.err = err }
+
+func (p *testSpan) End() { p.ended = true }
+
+type testTracer struct {
+	locker sync.Mutex
+	spans  []*testSpan
+}
+
+type testSpanKey struct{}
+
+func (p *testTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: spanName, parent: parent, attrs: make(map[string]interface{})}
+
+	p.locker.Lock()
+	p.spans = append(p.spans, span)
+	p.locker.Unlock()
+
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestClassicFactoryOfTracer(t *testing.T) {
+
+	var err error
+
+	tracer := &testTracer{}
+
+	factory := NewClassicFactory(nil, FactoryOptOfTracer(tracer))
+
+	if err = factory.Define("testObjBName", Singleton, "testObjectB", DefOptOfNewObjectFunc(newTestObjectB)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = factory.Define("testObjName", Prototype, "testObject",
+		DefOptOfObjectRef("ObjB", "testObjBName"),
+		DefOptOfObjectRef("ObjC.CValue", "testObjBName")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err = factory.GetObject("testObjName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Errorf("should start a span per getObject, got: %d", len(tracer.spans))
+		return
+	}
+
+	root, created, cached := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+
+	if root.parent != nil || created.parent != root || cached.parent != root {
+		t.Error("the spans of refs should nest in the span of owner")
+		return
+	}
+
+	if root.attrs[TraceAttrDefinition] != "testObjName" || root.attrs[TraceAttrScope] != "prototype" || root.attrs[TraceAttrCached] != false {
+		t.Errorf("bad attributes of span: %v", root.attrs)
+		return
+	}
+
+	if created.attrs[TraceAttrCached] != false || cached.attrs[TraceAttrCached] != true || !cached.ended {
+		t.Errorf("the span should carry whether the object came from cache: %v, %v", created.attrs, cached.attrs)
+		return
+	}
+}